@@ -1,15 +1,41 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"time"
 
+	"kubegreen/internal/config"
+	"kubegreen/internal/controller"
 	"kubegreen/internal/model"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
+	exportFormat := flag.String("export", "", "export the certificate inventory as json, csv or markdown instead of starting the UI")
+	output := flag.String("o", "", "file the export is written to, standard output by default")
+	notify := flag.Bool("notify", false, "post the expiring certificates to the configured webhooks instead of starting the UI")
+	flag.Parse()
+
+	if *notify {
+		if err := notifyCertificates(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *exportFormat != "" {
+		if err := exportCertificates(*exportFormat, *output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	model := model.NewModel()
 	p := tea.NewProgram(model)
 	if _, err := p.Run(); err != nil {
@@ -17,3 +43,90 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// exportCertificates writes the certificate inventory of the current
+// context without starting the UI
+func exportCertificates(formatName, output string) error {
+	format, err := controller.ParseExportFormat(formatName)
+	if err != nil {
+		return err
+	}
+	_, certs, err := loadCertificates()
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		return controller.ExportCertificates(os.Stdout, certs, format, time.Now())
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", output, err)
+	}
+	if err := controller.ExportCertificates(file, certs, format, time.Now()); err != nil {
+		file.Close()
+		return err
+	}
+	// A failed flush only shows up when the file is closed
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", output, err)
+	}
+	return nil
+}
+
+// notifyCertificates posts the expiring certificates of the current context
+// to the webhooks of the config, once per certificate per day
+func notifyCertificates() error {
+	cfg, certs, err := loadCertificates()
+	if err != nil {
+		return err
+	}
+	if len(cfg.Webhooks) == 0 {
+		return fmt.Errorf("no webhooks configured in %s", config.Path())
+	}
+	var webhooks []*controller.Webhook
+	for _, w := range cfg.Webhooks {
+		webhook, err := controller.NewWebhook(w.Name, w.URL, w.Format, w.Template, w.MinSeverity)
+		if err != nil {
+			return err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	notifier := controller.NewNotifier(webhooks, cfg.NotificationStatePath())
+	result, err := notifier.Notify(context.Background(), certs)
+	if result != nil {
+		for _, webhook := range webhooks {
+			fmt.Printf("%s: %d alerts sent\n", webhook.Name, result.Sent[webhook.Name])
+		}
+		if result.Deduplicated > 0 {
+			fmt.Printf("%d alerts already sent today\n", result.Deduplicated)
+		}
+	}
+	return err
+}
+
+// loadCertificates discovers the certificates of the current context with
+// the thresholds of the config. Sources that cannot be read are reported on
+// standard error, the certificates of the others are returned
+func loadCertificates() (*config.Config, []controller.CertInfo, error) {
+	ctlr, err := controller.NewContextController()
+	if err != nil {
+		return nil, nil, err
+	}
+	certCtl := controller.NewCertController(ctlr.GetClientset(), ctlr.GetDynamicClient())
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Using default settings: %v\n", err)
+	}
+	certCtl.SetExpiryThresholds(cfg.CertThresholdsFor)
+
+	certs, err := certCtl.GetCertificates()
+	if err != nil {
+		if len(certs) == 0 {
+			return nil, nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return cfg, certs, nil
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Config holds the user settings read from $HOME/.kubegreen/config.yaml
+type Config struct {
+	// VolumeUsageThreshold is the percentage of used capacity or inodes
+	// above which a volume is flagged in the volume list
+	VolumeUsageThreshold float64 `json:"volumeUsageThreshold"`
+	// AutoApproveCSRs makes certificate renewal approve its own CSR
+	// instead of waiting for an approver. Off by default
+	AutoApproveCSRs bool `json:"autoApproveCSRs"`
+	// CertWarningDays and CertCriticalDays are the number of days before
+	// expiry at which a certificate becomes warning and critical
+	CertWarningDays  int `json:"certWarningDays"`
+	CertCriticalDays int `json:"certCriticalDays"`
+	// CertNamespaceThresholds overrides the certificate thresholds per
+	// namespace. A zero value keeps the global threshold
+	CertNamespaceThresholds map[string]CertThresholds `json:"certNamespaceThresholds"`
+	// MetricsWindowMinutes is the span of metrics history shown in the
+	// metrics view
+	MetricsWindowMinutes int `json:"metricsWindowMinutes"`
+	// Webhooks are sent the expiring certificates by kubegreen -notify
+	Webhooks []WebhookConfig `json:"webhooks"`
+	// NotificationStateFile records the alerts sent today, see
+	// NotificationStatePath
+	NotificationStateFile string `json:"notificationStateFile"`
+}
+
+// WebhookConfig is a webhook notified of expiring certificates
+type WebhookConfig struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Format is the payload shape: json (default), slack or teams
+	Format string `json:"format"`
+	// Template is a Go template rendering the message of a certificate
+	Template string `json:"template"`
+	// MinSeverity is the least severe status notified, warning by default
+	MinSeverity string `json:"minSeverity"`
+}
+
+// CertThresholds are the certificate expiry thresholds of a namespace
+type CertThresholds struct {
+	WarningDays  int `json:"warningDays"`
+	CriticalDays int `json:"criticalDays"`
+}
+
+// CertThresholdsFor returns the warning and critical days of a namespace
+func (c *Config) CertThresholdsFor(namespace string) (warningDays, criticalDays int) {
+	warningDays, criticalDays = c.CertWarningDays, c.CertCriticalDays
+	if override, ok := c.CertNamespaceThresholds[namespace]; ok {
+		if override.WarningDays > 0 {
+			warningDays = override.WarningDays
+		}
+		if override.CriticalDays > 0 {
+			criticalDays = override.CriticalDays
+		}
+	}
+	return warningDays, criticalDays
+}
+
+// Default returns the settings used when no config file exists
+func Default() *Config {
+	return &Config{
+		VolumeUsageThreshold: 80,
+		CertWarningDays:      30,
+		CertCriticalDays:     7,
+		MetricsWindowMinutes: 30,
+	}
+}
+
+// Path returns the location of the config file
+func Path() string {
+	return filepath.Join(os.Getenv("HOME"), ".kubegreen", "config.yaml")
+}
+
+// NotificationStatePath returns the notification state file, next to the
+// config file unless set
+func (c *Config) NotificationStatePath() string {
+	if c.NotificationStateFile != "" {
+		return c.NotificationStateFile
+	}
+	return filepath.Join(os.Getenv("HOME"), ".kubegreen", "notifications.json")
+}
+
+// Load reads the config file. Settings missing from the file keep their
+// default value, and a missing file yields the defaults
+func Load() (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(Path())
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config: %v", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return Default(), fmt.Errorf("failed to parse %s: %v", Path(), err)
+	}
+
+	if cfg.VolumeUsageThreshold <= 0 || cfg.VolumeUsageThreshold > 100 {
+		return Default(), fmt.Errorf("volumeUsageThreshold must be between 0 and 100, got %v", cfg.VolumeUsageThreshold)
+	}
+	if cfg.MetricsWindowMinutes <= 0 {
+		return Default(), fmt.Errorf("metricsWindowMinutes must be positive, got %d", cfg.MetricsWindowMinutes)
+	}
+	if err := cfg.validateCertThresholds(); err != nil {
+		return Default(), err
+	}
+	return cfg, nil
+}
+
+func (c *Config) validateCertThresholds() error {
+	if c.CertCriticalDays < 0 || c.CertWarningDays < c.CertCriticalDays {
+		return fmt.Errorf("certWarningDays (%d) must be at least certCriticalDays (%d), which must not be negative",
+			c.CertWarningDays, c.CertCriticalDays)
+	}
+	for namespace, override := range c.CertNamespaceThresholds {
+		if override.WarningDays < 0 || override.CriticalDays < 0 {
+			return fmt.Errorf("certificate thresholds of namespace %s must not be negative", namespace)
+		}
+		warningDays, criticalDays := c.CertThresholdsFor(namespace)
+		if warningDays < criticalDays {
+			return fmt.Errorf("warningDays (%d) of namespace %s must be at least criticalDays (%d)",
+				warningDays, namespace, criticalDays)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CertDetails holds the X.509 fields of one certificate of a chain
+type CertDetails struct {
+	Subject            string
+	Issuer             string
+	SerialNumber       string
+	NotBefore          time.Time
+	NotAfter           time.Time
+	DNSNames           []string
+	IPAddresses        []string
+	EmailAddresses     []string
+	URIs               []string
+	KeyAlgorithm       string
+	KeySize            int
+	SignatureAlgorithm string
+	SHA256Fingerprint  string
+	KeyUsages          []string
+	ExtKeyUsages       []string
+	IsCA               bool
+}
+
+// SANs returns all subject alternative names of the certificate
+func (d CertDetails) SANs() []string {
+	var sans []string
+	sans = append(sans, d.DNSNames...)
+	sans = append(sans, d.IPAddresses...)
+	sans = append(sans, d.EmailAddresses...)
+	sans = append(sans, d.URIs...)
+	return sans
+}
+
+// parseCertificateChain decodes every CERTIFICATE block of a PEM bundle, in
+// the order they appear
+func parseCertificateChain(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate %d: %v", len(certs)+1, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found in PEM data")
+	}
+	return certs, nil
+}
+
+// chainOrdered reports whether every certificate of a chain is issued by
+// the one following it, as TLS servers are expected to send them
+func chainOrdered(certs []*x509.Certificate) bool {
+	for i := 0; i+1 < len(certs); i++ {
+		if !bytes.Equal(certs[i].RawIssuer, certs[i+1].RawSubject) {
+			return false
+		}
+		if certs[i].CheckSignatureFrom(certs[i+1]) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func certDetails(cert *x509.Certificate) CertDetails {
+	fingerprint := sha256.Sum256(cert.Raw)
+	details := CertDetails{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SerialNumber:       cert.SerialNumber.String(),
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		DNSNames:           cert.DNSNames,
+		EmailAddresses:     cert.EmailAddresses,
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		SHA256Fingerprint:  formatFingerprint(fingerprint[:]),
+		KeyUsages:          keyUsageNames(cert.KeyUsage),
+		ExtKeyUsages:       extKeyUsageNames(cert.ExtKeyUsage),
+		IsCA:               cert.IsCA,
+	}
+	for _, ip := range cert.IPAddresses {
+		details.IPAddresses = append(details.IPAddresses, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		details.URIs = append(details.URIs, uri.String())
+	}
+	details.KeyAlgorithm, details.KeySize = publicKeyInfo(cert.PublicKey)
+	return details
+}
+
+// publicKeyInfo returns the algorithm and size in bits of a public key
+func publicKeyInfo(key interface{}) (string, int) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return "RSA", k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA", k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	}
+	return "unknown", 0
+}
+
+func formatFingerprint(sum []byte) string {
+	encoded := strings.ToUpper(hex.EncodeToString(sum))
+	var parts []string
+	for i := 0; i < len(encoded); i += 2 {
+		parts = append(parts, encoded[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+var keyUsages = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "Digital Signature"},
+	{x509.KeyUsageContentCommitment, "Content Commitment"},
+	{x509.KeyUsageKeyEncipherment, "Key Encipherment"},
+	{x509.KeyUsageDataEncipherment, "Data Encipherment"},
+	{x509.KeyUsageKeyAgreement, "Key Agreement"},
+	{x509.KeyUsageCertSign, "Certificate Sign"},
+	{x509.KeyUsageCRLSign, "CRL Sign"},
+	{x509.KeyUsageEncipherOnly, "Encipher Only"},
+	{x509.KeyUsageDecipherOnly, "Decipher Only"},
+}
+
+func keyUsageNames(usage x509.KeyUsage) []string {
+	var names []string
+	for _, ku := range keyUsages {
+		if usage&ku.usage != 0 {
+			names = append(names, ku.name)
+		}
+	}
+	return names
+}
+
+var extKeyUsages = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "Any",
+	x509.ExtKeyUsageServerAuth:      "Server Auth",
+	x509.ExtKeyUsageClientAuth:      "Client Auth",
+	x509.ExtKeyUsageCodeSigning:     "Code Signing",
+	x509.ExtKeyUsageEmailProtection: "Email Protection",
+	x509.ExtKeyUsageTimeStamping:    "Time Stamping",
+	x509.ExtKeyUsageOCSPSigning:     "OCSP Signing",
+}
+
+func extKeyUsageNames(usages []x509.ExtKeyUsage) []string {
+	var names []string
+	for _, usage := range usages {
+		name, ok := extKeyUsages[usage]
+		if !ok {
+			name = fmt.Sprintf("Unknown (%d)", usage)
+		}
+		names = append(names, name)
+	}
+	return names
+}
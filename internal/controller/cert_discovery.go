@@ -0,0 +1,233 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Kinds of the objects certificates are discovered in
+const (
+	kindSecret                         = "Secret"
+	kindConfigMap                      = "ConfigMap"
+	kindValidatingWebhookConfiguration = "ValidatingWebhookConfiguration"
+	kindMutatingWebhookConfiguration   = "MutatingWebhookConfiguration"
+	kindAPIService                     = "APIService"
+	kindCustomResourceDefinition       = "CustomResourceDefinition"
+
+	tlsSecretFieldPath = "data[tls.crt]"
+)
+
+var pemCertificateHeader = []byte("-----BEGIN CERTIFICATE-----")
+
+var (
+	apiServiceGVR = schema.GroupVersionResource{
+		Group:    "apiregistration.k8s.io",
+		Version:  "v1",
+		Resource: "apiservices",
+	}
+	customResourceDefinitionGVR = schema.GroupVersionResource{
+		Group:    "apiextensions.k8s.io",
+		Version:  "v1",
+		Resource: "customresourcedefinitions",
+	}
+)
+
+// GetCertificates discovers the certificates of the cluster: the tls.crt of
+// TLS secrets, PEM certificates in other secret and ConfigMap keys, and the
+// caBundles of webhook configurations, APIServices and CRD conversion
+// webhooks. When some sources cannot be read, the certificates of the
+// other sources are returned along with an error naming the failed ones
+func (c *CertController) GetCertificates() ([]CertInfo, error) {
+	certs, err := c.GetTLSCertificates()
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []string
+	sources := []struct {
+		name string
+		list func(ctx context.Context) ([]CertInfo, error)
+	}{
+		{"secrets", c.secretBundleCertificates},
+		{"configmaps", c.configMapCertificates},
+		{"webhook configurations", c.webhookCertificates},
+		{"apiservices", c.apiServiceCertificates},
+		{"CRD conversion webhooks", c.crdCertificates},
+	}
+	for _, source := range sources {
+		found, err := source.list(context.TODO())
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", source.name, err))
+			continue
+		}
+		certs = append(certs, found...)
+	}
+
+	// Soonest expiry first
+	sort.SliceStable(certs, func(i, j int) bool {
+		return certs[i].NotAfter.Before(certs[j].NotAfter)
+	})
+	if len(failed) > 0 {
+		return certs, fmt.Errorf("failed to scan %s", strings.Join(failed, "; "))
+	}
+	return certs, nil
+}
+
+// bundleCertificates lists every certificate of a PEM bundle separately,
+// numbering the field path when the bundle holds several
+func (c *CertController) bundleCertificates(kind, namespace, name, fieldPath string, data []byte) []CertInfo {
+	if !bytes.Contains(data, pemCertificateHeader) {
+		return nil
+	}
+	chain, err := parseCertificateChain(data)
+	if err != nil {
+		return nil
+	}
+
+	var certs []CertInfo
+	for i := range chain {
+		path := fieldPath
+		if len(chain) > 1 {
+			path = fmt.Sprintf("%s[%d]", fieldPath, i)
+		}
+		certs = append(certs, c.newCertInfo(kind, namespace, name, path, chain[i:i+1]))
+	}
+	return certs
+}
+
+// secretBundleCertificates returns the PEM certificates of Opaque secrets
+// and of TLS secret keys other than tls.crt, such as ca.crt
+func (c *CertController) secretBundleCertificates(ctx context.Context) ([]CertInfo, error) {
+	secrets, err := c.clientset.CoreV1().Secrets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []CertInfo
+	for _, secret := range secrets.Items {
+		if secret.Type != corev1.SecretTypeOpaque && secret.Type != corev1.SecretTypeTLS && secret.Type != "" {
+			continue
+		}
+		for _, key := range sortedKeys(secret.Data) {
+			if secret.Type == corev1.SecretTypeTLS && key == corev1.TLSCertKey {
+				continue
+			}
+			path := fmt.Sprintf("data[%s]", key)
+			certs = append(certs, c.bundleCertificates(kindSecret, secret.Namespace, secret.Name, path, secret.Data[key])...)
+		}
+	}
+	return certs, nil
+}
+
+// configMapCertificates returns the PEM certificates of ConfigMaps, such as
+// kube-root-ca.crt
+func (c *CertController) configMapCertificates(ctx context.Context) ([]CertInfo, error) {
+	configMaps, err := c.clientset.CoreV1().ConfigMaps("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []CertInfo
+	for _, cm := range configMaps.Items {
+		keys := make([]string, 0, len(cm.Data))
+		for key := range cm.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			path := fmt.Sprintf("data[%s]", key)
+			certs = append(certs, c.bundleCertificates(kindConfigMap, cm.Namespace, cm.Name, path, []byte(cm.Data[key]))...)
+		}
+		for _, key := range sortedKeys(cm.BinaryData) {
+			path := fmt.Sprintf("binaryData[%s]", key)
+			certs = append(certs, c.bundleCertificates(kindConfigMap, cm.Namespace, cm.Name, path, cm.BinaryData[key])...)
+		}
+	}
+	return certs, nil
+}
+
+// webhookCertificates returns the caBundles of validating and mutating
+// webhook configurations
+func (c *CertController) webhookCertificates(ctx context.Context) ([]CertInfo, error) {
+	admission := c.clientset.AdmissionregistrationV1()
+	validating, err := admission.ValidatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	mutating, err := admission.MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []CertInfo
+	for _, config := range validating.Items {
+		for _, webhook := range config.Webhooks {
+			path := fmt.Sprintf("webhooks[%s].clientConfig.caBundle", webhook.Name)
+			certs = append(certs, c.bundleCertificates(kindValidatingWebhookConfiguration, "", config.Name, path, webhook.ClientConfig.CABundle)...)
+		}
+	}
+	for _, config := range mutating.Items {
+		for _, webhook := range config.Webhooks {
+			path := fmt.Sprintf("webhooks[%s].clientConfig.caBundle", webhook.Name)
+			certs = append(certs, c.bundleCertificates(kindMutatingWebhookConfiguration, "", config.Name, path, webhook.ClientConfig.CABundle)...)
+		}
+	}
+	return certs, nil
+}
+
+// apiServiceCertificates returns the caBundles of aggregated APIServices
+func (c *CertController) apiServiceCertificates(ctx context.Context) ([]CertInfo, error) {
+	return c.unstructuredCABundles(ctx, apiServiceGVR, kindAPIService, "spec", "caBundle")
+}
+
+// crdCertificates returns the caBundles of CRD conversion webhooks
+func (c *CertController) crdCertificates(ctx context.Context) ([]CertInfo, error) {
+	return c.unstructuredCABundles(ctx, customResourceDefinitionGVR, kindCustomResourceDefinition,
+		"spec", "conversion", "webhook", "clientConfig", "caBundle")
+}
+
+// unstructuredCABundles reads the base64 caBundle at fields of every object
+// of a cluster-scoped resource
+func (c *CertController) unstructuredCABundles(ctx context.Context, gvr schema.GroupVersionResource, kind string, fields ...string) ([]CertInfo, error) {
+	list, err := c.dynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	path := strings.Join(fields, ".")
+	var certs []CertInfo
+	for _, item := range list.Items {
+		encoded, _, _ := unstructured.NestedString(item.Object, fields...)
+		if encoded == "" {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		certs = append(certs, c.bundleCertificates(kind, "", item.GetName(), path, data)...)
+	}
+	return certs, nil
+}
+
+func sortedKeys(data map[string][]byte) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
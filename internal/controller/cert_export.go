@@ -0,0 +1,233 @@
+package controller
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportFormat is the file format of a certificate inventory
+type ExportFormat string
+
+const (
+	ExportJSON     ExportFormat = "json"
+	ExportCSV      ExportFormat = "csv"
+	ExportMarkdown ExportFormat = "markdown"
+)
+
+// ExportFormats lists the supported export formats
+var ExportFormats = []ExportFormat{ExportJSON, ExportCSV, ExportMarkdown}
+
+// ParseExportFormat returns the export format of a name, accepting md for
+// markdown
+func ParseExportFormat(name string) (ExportFormat, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return ExportJSON, nil
+	case "csv":
+		return ExportCSV, nil
+	case "markdown", "md":
+		return ExportMarkdown, nil
+	}
+	return "", fmt.Errorf("unknown export format %q, expected json, csv or markdown", name)
+}
+
+// Extension returns the file extension of the format
+func (f ExportFormat) Extension() string {
+	if f == ExportMarkdown {
+		return "md"
+	}
+	return string(f)
+}
+
+// certificateRecord is a certificate as written to JSON and CSV exports
+type certificateRecord struct {
+	Namespace         string   `json:"namespace"`
+	Kind              string   `json:"kind"`
+	Name              string   `json:"name"`
+	FieldPath         string   `json:"fieldPath"`
+	Subject           string   `json:"subject"`
+	Issuer            string   `json:"issuer"`
+	SANs              []string `json:"sans"`
+	SerialNumber      string   `json:"serialNumber"`
+	NotBefore         string   `json:"notBefore"`
+	NotAfter          string   `json:"notAfter"`
+	DaysRemaining     int      `json:"daysRemaining"`
+	Severity          string   `json:"severity"`
+	Checks            string   `json:"checks"`
+	SHA256Fingerprint string   `json:"sha256Fingerprint"`
+}
+
+var csvHeader = []string{
+	"namespace", "kind", "name", "fieldPath", "subject", "issuer", "sans", "serialNumber",
+	"notBefore", "notAfter", "daysRemaining", "severity", "checks", "sha256Fingerprint",
+}
+
+func newCertificateRecord(cert *CertInfo) certificateRecord {
+	record := certificateRecord{
+		Namespace:     cert.Namespace,
+		Kind:          cert.Kind,
+		Name:          cert.Name,
+		FieldPath:     cert.FieldPath,
+		Subject:       cert.Subject,
+		Issuer:        cert.Issuer,
+		SANs:          cert.SANs,
+		SerialNumber:  cert.SerialNumber,
+		NotBefore:     cert.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:      cert.NotAfter.UTC().Format(time.RFC3339),
+		DaysRemaining: cert.DaysRemaining,
+		Severity:      cert.Severity.String(),
+		Checks:        cert.Validation.Status(),
+	}
+	if record.SANs == nil {
+		record.SANs = []string{}
+	}
+	if len(cert.Chain) > 0 {
+		record.SHA256Fingerprint = cert.Chain[0].SHA256Fingerprint
+	}
+	return record
+}
+
+// sortForExport returns the certificates ordered by namespace, kind, name,
+// field path and serial number, so that successive reports diff cleanly
+func sortForExport(certs []CertInfo) []CertInfo {
+	sorted := append([]CertInfo(nil), certs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := &sorted[i], &sorted[j]
+		switch {
+		case a.Namespace != b.Namespace:
+			return a.Namespace < b.Namespace
+		case a.Kind != b.Kind:
+			return a.Kind < b.Kind
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		case a.FieldPath != b.FieldPath:
+			return a.FieldPath < b.FieldPath
+		}
+		return a.SerialNumber < b.SerialNumber
+	})
+	return sorted
+}
+
+// ExportCertificates writes a certificate inventory in the given format.
+// generated dates the Markdown report
+func ExportCertificates(w io.Writer, certs []CertInfo, format ExportFormat, generated time.Time) error {
+	certs = sortForExport(certs)
+	switch format {
+	case ExportJSON:
+		return exportJSON(w, certs)
+	case ExportCSV:
+		return exportCSV(w, certs)
+	case ExportMarkdown:
+		return exportMarkdown(w, certs, generated)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+func exportJSON(w io.Writer, certs []CertInfo) error {
+	records := make([]certificateRecord, 0, len(certs))
+	for i := range certs {
+		records = append(records, newCertificateRecord(&certs[i]))
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(records); err != nil {
+		return fmt.Errorf("failed to write JSON: %v", err)
+	}
+	return nil
+}
+
+func exportCSV(w io.Writer, certs []CertInfo) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV: %v", err)
+	}
+	for i := range certs {
+		r := newCertificateRecord(&certs[i])
+		row := []string{
+			r.Namespace, r.Kind, r.Name, r.FieldPath, r.Subject, r.Issuer, strings.Join(r.SANs, ";"), r.SerialNumber,
+			r.NotBefore, r.NotAfter, strconv.Itoa(r.DaysRemaining), r.Severity, r.Checks, r.SHA256Fingerprint,
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV: %v", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %v", err)
+	}
+	return nil
+}
+
+// exportMarkdown writes a report with a summary of the severities, then the
+// certificates of each namespace grouped by severity, the most severe first
+func exportMarkdown(w io.Writer, certs []CertInfo, generated time.Time) error {
+	var b strings.Builder
+	b.WriteString("# Certificate inventory\n\n")
+	b.WriteString(fmt.Sprintf("Generated %s, %d certificates.\n\n", generated.UTC().Format("2006-01-02"), len(certs)))
+
+	counts := make(map[Severity]int)
+	for _, cert := range certs {
+		counts[cert.Severity]++
+	}
+	b.WriteString("| Severity | Certificates |\n|---|---|\n")
+	for i := len(Severities) - 1; i >= 0; i-- {
+		b.WriteString(fmt.Sprintf("| %s | %d |\n", Severities[i], counts[Severities[i]]))
+	}
+
+	var namespaces []string
+	byNamespace := make(map[string][]CertInfo)
+	for _, cert := range certs {
+		if _, ok := byNamespace[cert.Namespace]; !ok {
+			namespaces = append(namespaces, cert.Namespace)
+		}
+		byNamespace[cert.Namespace] = append(byNamespace[cert.Namespace], cert)
+	}
+
+	for _, namespace := range namespaces {
+		title := "Namespace " + namespace
+		if namespace == "" {
+			title = "Cluster-scoped"
+		}
+		b.WriteString(fmt.Sprintf("\n## %s\n", title))
+		for i := len(Severities) - 1; i >= 0; i-- {
+			severity := Severities[i]
+			var rows []CertInfo
+			for _, cert := range byNamespace[namespace] {
+				if cert.Severity == severity {
+					rows = append(rows, cert)
+				}
+			}
+			if len(rows) == 0 {
+				continue
+			}
+			b.WriteString(fmt.Sprintf("\n### %s (%d)\n\n", severity, len(rows)))
+			b.WriteString("| Kind | Name | Field | Subject | Issuer | SANs | Expires | Days | Checks |\n")
+			b.WriteString("|---|---|---|---|---|---|---|---|---|\n")
+			for _, cert := range rows {
+				b.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s | %s | %d | %s |\n",
+					cert.Kind, markdownCell(cert.Name), markdownCell(cert.FieldPath), markdownCell(cert.Subject),
+					markdownCell(cert.Issuer), markdownCell(strings.Join(cert.SANs, ", ")),
+					cert.NotAfter.UTC().Format("2006-01-02"), cert.DaysRemaining, cert.Validation.Status()))
+			}
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write Markdown: %v", err)
+	}
+	return nil
+}
+
+// markdownCell escapes the pipes of a table cell
+func markdownCell(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(value, "|", "\\|")
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// testExportCerts returns a namespaced certificate whose subject needs CSV
+// quoting, then a cluster-scoped one whose name needs Markdown escaping
+func testExportCerts() []CertInfo {
+	return []CertInfo{
+		{
+			Kind:          "Secret",
+			Name:          "web-tls",
+			Namespace:     "shop",
+			FieldPath:     tlsSecretFieldPath,
+			Subject:       `CN=shop.example.com,O="Acme, Inc."`,
+			Issuer:        "CN=ca",
+			SANs:          []string{"shop.example.com", "www.shop.example.com"},
+			NotBefore:     date(2026, time.January, 1),
+			NotAfter:      date(2026, time.November, 1),
+			DaysRemaining: 16,
+			SerialNumber:  "2",
+			Severity:      SeverityWarning,
+			Chain:         []CertDetails{{SHA256Fingerprint: "AB:CD"}},
+			Validation:    CertValidation{CASource: "system roots"},
+		},
+		{
+			Kind:          "Secret",
+			Name:          "api|tls",
+			FieldPath:     tlsSecretFieldPath,
+			Subject:       "CN=api",
+			Issuer:        "CN=api",
+			NotBefore:     date(2025, time.October, 1),
+			NotAfter:      date(2026, time.October, 1),
+			DaysRemaining: -15,
+			SerialNumber:  "1",
+			Severity:      SeverityExpired,
+			Validation:    CertValidation{KeyError: keyMismatchError},
+		},
+	}
+}
+
+func TestExportCertificates(t *testing.T) {
+	tests := []struct {
+		format ExportFormat
+		want   string
+	}{
+		{ExportJSON, `[
+  {
+    "namespace": "",
+    "kind": "Secret",
+    "name": "api|tls",
+    "fieldPath": "data[tls.crt]",
+    "subject": "CN=api",
+    "issuer": "CN=api",
+    "sans": [],
+    "serialNumber": "1",
+    "notBefore": "2025-10-01T00:00:00Z",
+    "notAfter": "2026-10-01T00:00:00Z",
+    "daysRemaining": -15,
+    "severity": "Expired",
+    "checks": "Key mismatch",
+    "sha256Fingerprint": ""
+  },
+  {
+    "namespace": "shop",
+    "kind": "Secret",
+    "name": "web-tls",
+    "fieldPath": "data[tls.crt]",
+    "subject": "CN=shop.example.com,O=\"Acme, Inc.\"",
+    "issuer": "CN=ca",
+    "sans": [
+      "shop.example.com",
+      "www.shop.example.com"
+    ],
+    "serialNumber": "2",
+    "notBefore": "2026-01-01T00:00:00Z",
+    "notAfter": "2026-11-01T00:00:00Z",
+    "daysRemaining": 16,
+    "severity": "Warning",
+    "checks": "OK",
+    "sha256Fingerprint": "AB:CD"
+  }
+]
+`},
+		{ExportCSV, `namespace,kind,name,fieldPath,subject,issuer,sans,serialNumber,notBefore,notAfter,daysRemaining,severity,checks,sha256Fingerprint
+,Secret,api|tls,data[tls.crt],CN=api,CN=api,,1,2025-10-01T00:00:00Z,2026-10-01T00:00:00Z,-15,Expired,Key mismatch,
+shop,Secret,web-tls,data[tls.crt],"CN=shop.example.com,O=""Acme, Inc.""",CN=ca,shop.example.com;www.shop.example.com,2,2026-01-01T00:00:00Z,2026-11-01T00:00:00Z,16,Warning,OK,AB:CD
+`},
+		{ExportMarkdown, `# Certificate inventory
+
+Generated 2026-10-16, 2 certificates.
+
+| Severity | Certificates |
+|---|---|
+| Expired | 1 |
+| Critical | 0 |
+| Warning | 1 |
+| OK | 0 |
+
+## Cluster-scoped
+
+### Expired (1)
+
+| Kind | Name | Field | Subject | Issuer | SANs | Expires | Days | Checks |
+|---|---|---|---|---|---|---|---|---|
+| Secret | api\|tls | data[tls.crt] | CN=api | CN=api | - | 2026-10-01 | -15 | Key mismatch |
+
+## Namespace shop
+
+### Warning (1)
+
+| Kind | Name | Field | Subject | Issuer | SANs | Expires | Days | Checks |
+|---|---|---|---|---|---|---|---|---|
+| Secret | web-tls | data[tls.crt] | CN=shop.example.com,O="Acme, Inc." | CN=ca | shop.example.com, www.shop.example.com | 2026-11-01 | 16 | OK |
+`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var b strings.Builder
+			if err := ExportCertificates(&b, testExportCerts(), tt.format, date(2026, time.October, 16)); err != nil {
+				t.Fatalf("ExportCertificates: %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("export =\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+
+	if err := ExportCertificates(&strings.Builder{}, nil, "xml", time.Now()); err == nil {
+		t.Error("ExportCertificates accepted an unknown format")
+	}
+}
+
+func TestParseExportFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    ExportFormat
+		wantErr bool
+	}{
+		{"json", ExportJSON, false},
+		{"CSV", ExportCSV, false},
+		{"markdown", ExportMarkdown, false},
+		{"md", ExportMarkdown, false},
+		{"xml", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseExportFormat(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseExportFormat(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
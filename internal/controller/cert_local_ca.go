@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// LocalCA is a CA keypair used to sign certificates without the CSR API,
+// for dev and test clusters with a self-managed CA
+type LocalCA struct {
+	Cert *x509.Certificate
+	Key  crypto.Signer
+	// CertPEM is written to the ca.crt of re-issued secrets
+	CertPEM []byte
+}
+
+// NewLocalCA parses a PEM CA certificate and its private key
+func NewLocalCA(certPEM, keyPEM []byte) (*LocalCA, error) {
+	certs, err := parseCertificateChain(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA certificate: %v", err)
+	}
+	cert := certs[0]
+	if !cert.IsCA {
+		return nil, fmt.Errorf("%s is not a CA certificate", cert.Subject)
+	}
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA key: %v", err)
+	}
+	if !publicKeyEqual(key, cert) {
+		return nil, fmt.Errorf("the CA key does not match the CA certificate")
+	}
+	return &LocalCA{
+		Cert:    cert,
+		Key:     key,
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+	}, nil
+}
+
+// LoadLocalCAFromFiles reads a CA keypair from PEM files
+func LoadLocalCAFromFiles(certFile, keyFile string) (*LocalCA, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %v", err)
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA key: %v", err)
+	}
+	return NewLocalCA(certPEM, keyPEM)
+}
+
+// LoadLocalCAFromSecret reads a CA keypair from the tls.crt and tls.key of
+// a secret, or from its ca.crt and ca.key
+func (c *CertController) LoadLocalCAFromSecret(namespace, name string) (*LocalCA, error) {
+	secret, err := c.clientset.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CA secret: %v", err)
+	}
+
+	certPEM, keyPEM := secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		certPEM, keyPEM = secret.Data["ca.crt"], secret.Data["ca.key"]
+	}
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		return nil, fmt.Errorf("secret %s/%s has neither tls.crt and tls.key nor ca.crt and ca.key", namespace, name)
+	}
+	return NewLocalCA(certPEM, keyPEM)
+}
+
+// ReissueWithLocalCA signs a new certificate for a TLS secret with a local
+// CA. The subject, SANs, usages, key type and size and validity length of
+// the current certificate are kept. tls.crt, tls.key and ca.crt are written
+// in a single update of the secret
+func (c *CertController) ReissueWithLocalCA(ctx context.Context, namespace, name string, ca *LocalCA) (*x509.Certificate, error) {
+	secrets := c.clientset.CoreV1().Secrets(namespace)
+	secret, err := secrets.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret: %v", err)
+	}
+	chain, err := parseCertificateChain(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return nil, err
+	}
+	current := chain[0]
+
+	algorithm, size := publicKeyInfo(current.PublicKey)
+	if algorithm == "unknown" {
+		algorithm, size = "RSA", 2048
+	}
+	privateKey, err := generatePrivateKey(algorithm, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate private key: %v", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %v", err)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:   serial,
+		Subject:        current.Subject,
+		DNSNames:       current.DNSNames,
+		IPAddresses:    current.IPAddresses,
+		EmailAddresses: current.EmailAddresses,
+		URIs:           current.URIs,
+		NotBefore:      now,
+		NotAfter:       now.Add(current.NotAfter.Sub(current.NotBefore)),
+		KeyUsage:       current.KeyUsage,
+		ExtKeyUsage:    current.ExtKeyUsage,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, privateKey.Public(), ca.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign certificate: %v", err)
+	}
+	issued, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signed certificate: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if !bytes.Equal(ca.Cert.RawIssuer, ca.Cert.RawSubject) {
+		// An intermediate CA is sent along with the leaf
+		certPEM = append(certPEM, ca.CertPEM...)
+	}
+	keyPEM, err := encodePrivateKeyToPEM(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if secret == nil {
+			var err error
+			if secret, err = secrets.Get(ctx, name, metav1.GetOptions{}); err != nil {
+				return err
+			}
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[corev1.TLSCertKey] = certPEM
+		secret.Data[corev1.TLSPrivateKeyKey] = keyPEM
+		secret.Data["ca.crt"] = ca.CertPEM
+		_, err := secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		if err != nil {
+			secret = nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update secret: %v", err)
+	}
+	return issued, nil
+}
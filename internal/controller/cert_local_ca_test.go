@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// testKeyPair is a certificate and its key generated for a test
+type testKeyPair struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCertificate signs template with parent, or self-signs it when
+// parent is nil
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *testKeyPair) *testKeyPair {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	issuer, signer := template, key
+	if parent != nil {
+		issuer, signer = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, key.Public(), signer)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	keyPEM, err := encodePrivateKeyToPEM(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	return &testKeyPair{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  keyPEM,
+	}
+}
+
+func newTestCA(t *testing.T, name string) *testKeyPair {
+	return newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil)
+}
+
+func newTestServingCertificate(t *testing.T, ca *testKeyPair, validity time.Duration, dnsNames ...string) *testKeyPair {
+	notBefore := time.Now().Add(-time.Hour)
+	return newTestCertificate(t, &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName:         dnsNames[0],
+			Organization:       []string{"kubegreen"},
+			OrganizationalUnit: []string{"platform"},
+		},
+		DNSNames:    dnsNames,
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.1")},
+		NotBefore:   notBefore,
+		NotAfter:    notBefore.Add(validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+}
+
+func newTestTLSSecret(namespace, name string, pair *testKeyPair) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       pair.certPEM,
+			corev1.TLSPrivateKeyKey: pair.keyPEM,
+		},
+	}
+}
+
+func TestNewLocalCA(t *testing.T) {
+	ca := newTestCA(t, "test-ca")
+	if _, err := NewLocalCA(ca.certPEM, ca.keyPEM); err != nil {
+		t.Fatalf("NewLocalCA: %v", err)
+	}
+
+	other := newTestCA(t, "other-ca")
+	if _, err := NewLocalCA(ca.certPEM, other.keyPEM); err == nil {
+		t.Error("NewLocalCA accepted the key of another CA")
+	}
+
+	leaf := newTestServingCertificate(t, ca, 24*time.Hour, "app.example.com")
+	if _, err := NewLocalCA(leaf.certPEM, leaf.keyPEM); err == nil {
+		t.Error("NewLocalCA accepted a certificate that is not a CA")
+	}
+}
+
+func TestReissueWithLocalCA(t *testing.T) {
+	oldCA := newTestCA(t, "old-ca")
+	validity := 90 * 24 * time.Hour
+	current := newTestServingCertificate(t, oldCA, validity, "app.example.com", "www.example.com")
+	clientset := fake.NewSimpleClientset(newTestTLSSecret("default", "app-tls", current))
+	c := NewCertController(clientset, nil)
+
+	newCA := newTestCA(t, "local-ca")
+	ca, err := NewLocalCA(newCA.certPEM, newCA.keyPEM)
+	if err != nil {
+		t.Fatalf("NewLocalCA: %v", err)
+	}
+	clientset.ClearActions()
+
+	issued, err := c.ReissueWithLocalCA(context.Background(), "default", "app-tls", ca)
+	if err != nil {
+		t.Fatalf("ReissueWithLocalCA: %v", err)
+	}
+
+	var updates []*corev1.Secret
+	for _, action := range clientset.Actions() {
+		if update, ok := action.(k8stesting.UpdateAction); ok {
+			updates = append(updates, update.GetObject().(*corev1.Secret))
+		}
+	}
+	if len(updates) != 1 {
+		t.Fatalf("secret updated %d times, want a single update", len(updates))
+	}
+	data := updates[0].Data
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey, "ca.crt"} {
+		if len(data[key]) == 0 {
+			t.Errorf("%s not written by the update", key)
+		}
+	}
+	if string(data["ca.crt"]) != string(newCA.certPEM) {
+		t.Error("ca.crt is not the local CA certificate")
+	}
+	if _, err := tls.X509KeyPair(data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey]); err != nil {
+		t.Errorf("tls.crt and tls.key do not match: %v", err)
+	}
+
+	chain, err := parseCertificateChain(data[corev1.TLSCertKey])
+	if err != nil {
+		t.Fatalf("written tls.crt: %v", err)
+	}
+	written := chain[0]
+	if !written.Equal(issued) {
+		t.Error("written tls.crt is not the returned certificate")
+	}
+	if !reflect.DeepEqual(written.DNSNames, current.cert.DNSNames) {
+		t.Errorf("DNS names = %v, want %v", written.DNSNames, current.cert.DNSNames)
+	}
+	if len(written.IPAddresses) != 1 || !written.IPAddresses[0].Equal(current.cert.IPAddresses[0]) {
+		t.Errorf("IP addresses = %v, want %v", written.IPAddresses, current.cert.IPAddresses)
+	}
+	if written.Subject.String() != current.cert.Subject.String() {
+		t.Errorf("subject = %s, want %s", written.Subject, current.cert.Subject)
+	}
+	if got := written.NotAfter.Sub(written.NotBefore); got != validity {
+		t.Errorf("validity = %s, want %s", got, validity)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(newCA.cert)
+	_, err = written.Verify(x509.VerifyOptions{
+		DNSName:   "www.example.com",
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if err != nil {
+		t.Errorf("re-issued certificate does not verify against the local CA: %v", err)
+	}
+}
+
+func TestReissueWithLocalCAIntermediate(t *testing.T) {
+	root := newTestCA(t, "root-ca")
+	intermediate := newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "intermediate-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, root)
+	current := newTestServingCertificate(t, root, 30*24*time.Hour, "app.example.com")
+	clientset := fake.NewSimpleClientset(newTestTLSSecret("default", "app-tls", current))
+	c := NewCertController(clientset, nil)
+
+	ca, err := NewLocalCA(intermediate.certPEM, intermediate.keyPEM)
+	if err != nil {
+		t.Fatalf("NewLocalCA: %v", err)
+	}
+	if _, err := c.ReissueWithLocalCA(context.Background(), "default", "app-tls", ca); err != nil {
+		t.Fatalf("ReissueWithLocalCA: %v", err)
+	}
+
+	secret, err := clientset.CoreV1().Secrets("default").Get(context.Background(), "app-tls", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get secret: %v", err)
+	}
+	chain, err := parseCertificateChain(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		t.Fatalf("written tls.crt: %v", err)
+	}
+	if len(chain) != 2 || !chain[1].Equal(intermediate.cert) {
+		t.Fatalf("tls.crt holds %d certificates, want the leaf and the intermediate CA", len(chain))
+	}
+
+	roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+	roots.AddCert(root.cert)
+	intermediates.AddCert(chain[1])
+	_, err = chain[0].Verify(x509.VerifyOptions{
+		DNSName:       "app.example.com",
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		t.Errorf("re-issued certificate does not verify through the intermediate CA: %v", err)
+	}
+}
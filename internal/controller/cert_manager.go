@@ -0,0 +1,283 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	certManagerGroup = "cert-manager.io"
+	// certificateNameAnnotation is set by cert-manager on the secrets and
+	// CertificateRequests of a Certificate
+	certificateNameAnnotation = "cert-manager.io/certificate-name"
+	// certificateRevisionAnnotation numbers the CertificateRequests of a
+	// Certificate
+	certificateRevisionAnnotation = "cert-manager.io/certificate-revision"
+
+	certManagerIssuingCondition = "Issuing"
+)
+
+var (
+	certManagerCertificateGVR = schema.GroupVersionResource{
+		Group:    certManagerGroup,
+		Version:  "v1",
+		Resource: "certificates",
+	}
+	certManagerRequestGVR = schema.GroupVersionResource{
+		Group:    certManagerGroup,
+		Version:  "v1",
+		Resource: "certificaterequests",
+	}
+	certManagerIssuerGVR = schema.GroupVersionResource{
+		Group:    certManagerGroup,
+		Version:  "v1",
+		Resource: "issuers",
+	}
+	certManagerClusterIssuerGVR = schema.GroupVersionResource{
+		Group:    certManagerGroup,
+		Version:  "v1",
+		Resource: "clusterissuers",
+	}
+)
+
+// CertManagerCondition is a status condition of a cert-manager resource
+type CertManagerCondition struct {
+	Type    string
+	Status  string
+	Reason  string
+	Message string
+}
+
+// CertManagerResource is a cert-manager object with its conditions
+type CertManagerResource struct {
+	Kind       string
+	Namespace  string
+	Name       string
+	Conditions []CertManagerCondition
+	// External is set for issuers of another API group, whose conditions
+	// are not read
+	External bool
+}
+
+// Ready reports whether the Ready condition of the resource is True
+func (r *CertManagerResource) Ready() bool {
+	for _, cond := range r.Conditions {
+		if cond.Type == "Ready" {
+			return cond.Status == "True"
+		}
+	}
+	return false
+}
+
+// CertManagerInfo holds the cert-manager resources behind a TLS secret
+type CertManagerInfo struct {
+	Certificate CertManagerResource
+	Issuer      CertManagerResource
+	// Request is the latest CertificateRequest, nil when none exists
+	Request     *CertManagerResource
+	RenewalTime time.Time
+	Revision    int64
+}
+
+// Issuing reports whether cert-manager is issuing the certificate
+func (i *CertManagerInfo) Issuing() bool {
+	for _, cond := range i.Certificate.Conditions {
+		if cond.Type == certManagerIssuingCondition {
+			return cond.Status == "True"
+		}
+	}
+	return false
+}
+
+// GetCertManagerInfo resolves the Certificate, issuer and latest
+// CertificateRequest of a cert-manager Certificate. It returns nil when the
+// Certificate does not exist, so its secret is no longer managed
+func (c *CertController) GetCertManagerInfo(namespace, name string) (*CertManagerInfo, error) {
+	ctx := context.TODO()
+
+	cert, err := c.dynamicClient.Resource(certManagerCertificateGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Certificate %s/%s: %v", namespace, name, err)
+	}
+
+	info := &CertManagerInfo{
+		Certificate: certManagerResource(cert),
+	}
+	info.Revision, _, _ = unstructured.NestedInt64(cert.Object, "status", "revision")
+	if renewal, _, _ := unstructured.NestedString(cert.Object, "status", "renewalTime"); renewal != "" {
+		info.RenewalTime, _ = time.Parse(time.RFC3339, renewal)
+	}
+
+	issuer, err := c.getCertManagerIssuer(ctx, cert)
+	if err != nil {
+		return nil, err
+	}
+	info.Issuer = *issuer
+
+	request, err := c.latestCertificateRequest(ctx, cert)
+	if err != nil {
+		return nil, err
+	}
+	info.Request = request
+	return info, nil
+}
+
+func (c *CertController) getCertManagerIssuer(ctx context.Context, cert *unstructured.Unstructured) (*CertManagerResource, error) {
+	name, _, _ := unstructured.NestedString(cert.Object, "spec", "issuerRef", "name")
+	kind, _, _ := unstructured.NestedString(cert.Object, "spec", "issuerRef", "kind")
+	group, _, _ := unstructured.NestedString(cert.Object, "spec", "issuerRef", "group")
+	if kind == "" {
+		kind = "Issuer"
+	}
+
+	issuer := &CertManagerResource{Kind: kind, Name: name}
+	if group != "" && group != certManagerGroup {
+		issuer.Kind = kind + "." + group
+		issuer.External = true
+		return issuer, nil
+	}
+
+	var obj *unstructured.Unstructured
+	var err error
+	if kind == "ClusterIssuer" {
+		obj, err = c.dynamicClient.Resource(certManagerClusterIssuerGVR).Get(ctx, name, metav1.GetOptions{})
+	} else {
+		issuer.Namespace = cert.GetNamespace()
+		obj, err = c.dynamicClient.Resource(certManagerIssuerGVR).Namespace(issuer.Namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	if errors.IsNotFound(err) {
+		issuer.Conditions = []CertManagerCondition{{
+			Type:    "Ready",
+			Status:  "False",
+			Reason:  "NotFound",
+			Message: fmt.Sprintf("%s %s does not exist", kind, name),
+		}}
+		return issuer, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s %s: %v", kind, name, err)
+	}
+	issuer.Conditions = certManagerConditions(obj)
+	return issuer, nil
+}
+
+// latestCertificateRequest returns the CertificateRequest of the highest
+// revision of a Certificate
+func (c *CertController) latestCertificateRequest(ctx context.Context, cert *unstructured.Unstructured) (*CertManagerResource, error) {
+	list, err := c.dynamicClient.Resource(certManagerRequestGVR).Namespace(cert.GetNamespace()).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CertificateRequests: %v", err)
+	}
+
+	var latest *unstructured.Unstructured
+	var latestRevision int64 = -1
+	for i := range list.Items {
+		item := &list.Items[i]
+		if !ownedBy(item, cert) && item.GetAnnotations()[certificateNameAnnotation] != cert.GetName() {
+			continue
+		}
+		revision, err := strconv.ParseInt(item.GetAnnotations()[certificateRevisionAnnotation], 10, 64)
+		if err != nil {
+			revision = 0
+		}
+		if latest == nil || revision > latestRevision ||
+			(revision == latestRevision && item.GetCreationTimestamp().After(latest.GetCreationTimestamp().Time)) {
+			latest, latestRevision = item, revision
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	request := certManagerResource(latest)
+	return &request, nil
+}
+
+// TriggerCertManagerRenewal asks cert-manager to re-issue a Certificate by
+// setting its Issuing condition, as cmctl renew does
+func (c *CertController) TriggerCertManagerRenewal(namespace, name string) error {
+	ctx := context.TODO()
+	resource := c.dynamicClient.Resource(certManagerCertificateGVR).Namespace(namespace)
+
+	cert, err := resource.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get Certificate %s/%s: %v", namespace, name, err)
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(cert.Object, "status", "conditions")
+	issuing := map[string]interface{}{
+		"type":               certManagerIssuingCondition,
+		"status":             "True",
+		"reason":             "ManuallyTriggered",
+		"message":            "Certificate re-issuance manually triggered",
+		"lastTransitionTime": time.Now().UTC().Format(time.RFC3339),
+	}
+	replaced := false
+	for i, cond := range conditions {
+		fields, ok := cond.(map[string]interface{})
+		if !ok || fields["type"] != certManagerIssuingCondition {
+			continue
+		}
+		if fields["status"] == "True" {
+			return fmt.Errorf("Certificate %s/%s is already being issued", namespace, name)
+		}
+		conditions[i] = issuing
+		replaced = true
+	}
+	if !replaced {
+		conditions = append(conditions, issuing)
+	}
+	if err := unstructured.SetNestedSlice(cert.Object, conditions, "status", "conditions"); err != nil {
+		return fmt.Errorf("failed to set Issuing condition: %v", err)
+	}
+
+	if _, err := resource.UpdateStatus(ctx, cert, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to trigger renewal of Certificate %s/%s: %v", namespace, name, err)
+	}
+	return nil
+}
+
+func certManagerResource(obj *unstructured.Unstructured) CertManagerResource {
+	return CertManagerResource{
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		Conditions: certManagerConditions(obj),
+	}
+}
+
+func certManagerConditions(obj *unstructured.Unstructured) []CertManagerCondition {
+	items, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	var conditions []CertManagerCondition
+	for _, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var cond CertManagerCondition
+		cond.Type, _, _ = unstructured.NestedString(fields, "type")
+		cond.Status, _, _ = unstructured.NestedString(fields, "status")
+		cond.Reason, _, _ = unstructured.NestedString(fields, "reason")
+		cond.Message, _, _ = unstructured.NestedString(fields, "message")
+		conditions = append(conditions, cond)
+	}
+	return conditions
+}
+
+func ownedBy(obj, owner *unstructured.Unstructured) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true
+		}
+	}
+	return false
+}
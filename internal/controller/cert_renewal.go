@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// minCSRDuration is the shortest ExpirationSeconds the API server accepts
+	minCSRDuration     = 10 * time.Minute
+	defaultCSRDuration = 365 * 24 * time.Hour
+	nodeUserPrefix     = "system:node:"
+	// legacyUnknownSignerName signs serving certificates that are not
+	// kubelet ones, when the controller manager is configured for it
+	legacyUnknownSignerName = "kubernetes.io/legacy-unknown"
+)
+
+// RenewalRequest describes the certificate requested when renewing a TLS
+// secret. RenewalDefaults fills it from the current certificate
+type RenewalRequest struct {
+	// Subject is the subject of the current certificate. CommonName and
+	// Organizations replace its CN and O
+	Subject       pkix.Name
+	CommonName    string
+	Organizations []string
+	DNSNames      []string
+	IPAddresses   []string
+	// KeyAlgorithm is RSA, ECDSA or Ed25519
+	KeyAlgorithm string
+	// KeySize is the RSA modulus or ECDSA curve size in bits
+	KeySize    int
+	SignerName string
+	Usages     []certificatesv1.KeyUsage
+	Duration   time.Duration
+}
+
+// RenewalDefaults returns a renewal request copying the subject, SANs, key
+// type and validity of the certificate in a TLS secret, with a signer and
+// usages matching its purpose
+func (c *CertController) RenewalDefaults(namespace, name string) (*RenewalRequest, error) {
+	secret, err := c.clientset.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret: %v", err)
+	}
+	chain, err := parseCertificateChain(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return nil, err
+	}
+	return renewalDefaults(chain[0]), nil
+}
+
+func renewalDefaults(cert *x509.Certificate) *RenewalRequest {
+	req := &RenewalRequest{
+		Subject:       cert.Subject,
+		CommonName:    cert.Subject.CommonName,
+		Organizations: cert.Subject.Organization,
+		DNSNames:      cert.DNSNames,
+		Duration:      cert.NotAfter.Sub(cert.NotBefore).Round(time.Hour),
+	}
+	for _, ip := range cert.IPAddresses {
+		req.IPAddresses = append(req.IPAddresses, ip.String())
+	}
+	req.KeyAlgorithm, req.KeySize = publicKeyInfo(cert.PublicKey)
+	if req.KeyAlgorithm == "unknown" {
+		req.KeyAlgorithm, req.KeySize = "RSA", 2048
+	}
+	if req.Duration < minCSRDuration {
+		req.Duration = defaultCSRDuration
+	}
+
+	serving, client := false, false
+	for _, usage := range cert.ExtKeyUsage {
+		switch usage {
+		case x509.ExtKeyUsageServerAuth:
+			serving = true
+		case x509.ExtKeyUsageClientAuth:
+			client = true
+		}
+	}
+	if !serving && !client {
+		// Without extended usages a certificate with SANs is most likely
+		// a serving certificate
+		serving = len(cert.DNSNames) > 0 || len(cert.IPAddresses) > 0
+		client = !serving
+	}
+
+	req.Usages = []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature}
+	if req.KeyAlgorithm == "RSA" {
+		req.Usages = append(req.Usages, certificatesv1.UsageKeyEncipherment)
+	}
+	if serving {
+		req.Usages = append(req.Usages, certificatesv1.UsageServerAuth)
+	}
+	if client {
+		req.Usages = append(req.Usages, certificatesv1.UsageClientAuth)
+	}
+
+	node := strings.HasPrefix(req.CommonName, nodeUserPrefix)
+	switch {
+	case serving && node:
+		req.SignerName = certificatesv1.KubeletServingSignerName
+	case serving:
+		req.SignerName = legacyUnknownSignerName
+	case node:
+		req.SignerName = certificatesv1.KubeAPIServerClientKubeletSignerName
+	default:
+		req.SignerName = certificatesv1.KubeAPIServerClientSignerName
+	}
+	return req
+}
+
+// Validate checks the request before a key is generated for it
+func (r *RenewalRequest) Validate() error {
+	if r.CommonName == "" && len(r.DNSNames) == 0 && len(r.IPAddresses) == 0 {
+		return fmt.Errorf("a common name or a SAN is required")
+	}
+	for _, ip := range r.IPAddresses {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid IP address %q", ip)
+		}
+	}
+	if r.SignerName == "" {
+		return fmt.Errorf("a signer name is required")
+	}
+	if len(r.Usages) == 0 {
+		return fmt.Errorf("at least one usage is required")
+	}
+	if r.Duration < minCSRDuration {
+		return fmt.Errorf("the duration must be at least %s", minCSRDuration)
+	}
+
+	switch r.KeyAlgorithm {
+	case "RSA":
+		if r.KeySize < 2048 || r.KeySize > 8192 {
+			return fmt.Errorf("RSA keys must be between 2048 and 8192 bits")
+		}
+	case "ECDSA":
+		if _, err := ellipticCurve(r.KeySize); err != nil {
+			return err
+		}
+	case "Ed25519":
+	default:
+		return fmt.Errorf("unsupported key algorithm %q, use RSA, ECDSA or Ed25519", r.KeyAlgorithm)
+	}
+	return nil
+}
+
+// certificateRequest builds the x509 CSR template of the request
+func (r *RenewalRequest) certificateRequest() *x509.CertificateRequest {
+	subject := r.Subject
+	subject.CommonName = r.CommonName
+	subject.Organization = r.Organizations
+	template := &x509.CertificateRequest{
+		Subject:  subject,
+		DNSNames: r.DNSNames,
+	}
+	for _, ip := range r.IPAddresses {
+		template.IPAddresses = append(template.IPAddresses, net.ParseIP(ip))
+	}
+	return template
+}
+
+func generatePrivateKey(algorithm string, size int) (crypto.Signer, error) {
+	switch algorithm {
+	case "RSA":
+		return rsa.GenerateKey(rand.Reader, size)
+	case "ECDSA":
+		curve, err := ellipticCurve(size)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	case "Ed25519":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	}
+	return nil, fmt.Errorf("unsupported key algorithm %q", algorithm)
+}
+
+func ellipticCurve(size int) (elliptic.Curve, error) {
+	switch size {
+	case 256:
+		return elliptic.P256(), nil
+	case 384:
+		return elliptic.P384(), nil
+	case 521:
+		return elliptic.P521(), nil
+	}
+	return nil, fmt.Errorf("ECDSA keys must be 256, 384 or 521 bits")
+}
@@ -0,0 +1,70 @@
+package controller
+
+import "time"
+
+// Severity ranks how close a certificate is to its expiry
+type Severity int
+
+const (
+	SeverityOK Severity = iota
+	SeverityWarning
+	SeverityCritical
+	SeverityExpired
+)
+
+// Severities lists every severity, the least severe first
+var Severities = []Severity{SeverityOK, SeverityWarning, SeverityCritical, SeverityExpired}
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "Warning"
+	case SeverityCritical:
+		return "Critical"
+	case SeverityExpired:
+		return "Expired"
+	}
+	return "OK"
+}
+
+// ThresholdsFunc returns the number of days before expiry at which the
+// certificates of a namespace become warning and critical
+type ThresholdsFunc func(namespace string) (warningDays, criticalDays int)
+
+const (
+	defaultWarningDays  = 30
+	defaultCriticalDays = 7
+)
+
+func defaultThresholds(string) (int, int) {
+	return defaultWarningDays, defaultCriticalDays
+}
+
+// SetExpiryThresholds sets the thresholds used to compute the severity of
+// certificates. Without them, certificates are warning 30 days and
+// critical 7 days before they expire
+func (c *CertController) SetExpiryThresholds(thresholds ThresholdsFunc) {
+	c.thresholds = thresholds
+}
+
+// severity returns the severity of a certificate of the namespace expiring
+// at notAfter
+func (c *CertController) severity(namespace string, notAfter time.Time) Severity {
+	thresholds := c.thresholds
+	if thresholds == nil {
+		thresholds = defaultThresholds
+	}
+	warningDays, criticalDays := thresholds(namespace)
+
+	remaining := time.Until(notAfter)
+	day := 24 * time.Hour
+	switch {
+	case remaining <= 0:
+		return SeverityExpired
+	case remaining <= time.Duration(criticalDays)*day:
+		return SeverityCritical
+	case remaining <= time.Duration(warningDays)*day:
+		return SeverityWarning
+	}
+	return SeverityOK
+}
@@ -0,0 +1,200 @@
+package controller
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const keyMismatchError = "tls.key does not match the certificate's public key"
+
+// CertValidation is the result of checking a TLS secret's key and chain
+type CertValidation struct {
+	// KeyError is empty when tls.key holds the private key of the leaf
+	KeyError string
+	// ChainError is empty when the chain verified against CASource
+	ChainError string
+	// CASource describes the roots the chain was verified against
+	CASource string
+}
+
+// Status summarizes the validation for the certificate list
+func (v CertValidation) Status() string {
+	switch {
+	case v == (CertValidation{}):
+		// Only the certificates of TLS secrets are validated
+		return "-"
+	case v.KeyError == keyMismatchError:
+		return "Key mismatch"
+	case v.KeyError != "":
+		return "Invalid key"
+	case v.ChainError != "":
+		return "Untrusted chain"
+	}
+	return "OK"
+}
+
+// validateTLSSecret checks the key and chain of a TLS secret. The chain is
+// verified against roots if given, against the secret's ca.crt otherwise,
+// and against the system roots when the secret has no ca.crt
+func validateTLSSecret(secret *corev1.Secret, chain []*x509.Certificate, roots *x509.CertPool, rootsSource string) CertValidation {
+	var v CertValidation
+
+	if keyData, ok := secret.Data[corev1.TLSPrivateKeyKey]; !ok || len(keyData) == 0 {
+		v.KeyError = "tls.key is missing"
+	} else if err := checkKeyMatches(keyData, chain[0]); err != nil {
+		v.KeyError = err.Error()
+	}
+
+	if roots == nil {
+		if caData, ok := secret.Data["ca.crt"]; ok && len(caData) > 0 {
+			pool, err := certPoolFromPEM(caData)
+			if err != nil {
+				v.ChainError = fmt.Sprintf("invalid ca.crt: %v", err)
+				return v
+			}
+			roots, rootsSource = pool, "ca.crt"
+		} else {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				v.ChainError = fmt.Sprintf("no ca.crt and no system roots: %v", err)
+				return v
+			}
+			roots, rootsSource = pool, "system roots"
+		}
+	}
+	v.CASource = rootsSource
+	if err := verifyChain(chain, roots); err != nil {
+		v.ChainError = err.Error()
+	}
+	return v
+}
+
+// ValidateCertificate checks the key and chain of a TLS secret. When
+// caNamespace and caName are set, the chain is verified against the ca.crt
+// (or tls.crt) of that secret instead of the secret's own ca.crt
+func (c *CertController) ValidateCertificate(namespace, name, caNamespace, caName string) (*CertValidation, error) {
+	ctx := context.TODO()
+
+	secret, err := c.clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret: %v", err)
+	}
+	chain, err := parseCertificateChain(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return nil, err
+	}
+
+	var roots *x509.CertPool
+	source := ""
+	if caName != "" {
+		caSecret, err := c.clientset.CoreV1().Secrets(caNamespace).Get(ctx, caName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get CA secret: %v", err)
+		}
+		caData := caSecret.Data["ca.crt"]
+		if len(caData) == 0 {
+			caData = caSecret.Data[corev1.TLSCertKey]
+		}
+		if len(caData) == 0 {
+			return nil, fmt.Errorf("secret %s/%s has neither ca.crt nor tls.crt", caNamespace, caName)
+		}
+		roots, err = certPoolFromPEM(caData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CA in secret %s/%s: %v", caNamespace, caName, err)
+		}
+		source = fmt.Sprintf("secret %s/%s", caNamespace, caName)
+	}
+
+	v := validateTLSSecret(secret, chain, roots, source)
+	return &v, nil
+}
+
+func certPoolFromPEM(data []byte) (*x509.CertPool, error) {
+	certs, err := parseCertificateChain(data)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+func verifyChain(chain []*x509.Certificate, roots *x509.CertPool) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+// parsePrivateKey decodes the first private key of a PEM bundle in PKCS#1,
+// SEC 1 (EC) or PKCS#8 form
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no private key found in PEM data")
+		}
+
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			switch k := key.(type) {
+			case *rsa.PrivateKey:
+				return k, nil
+			case *ecdsa.PrivateKey:
+				return k, nil
+			case ed25519.PrivateKey:
+				return k, nil
+			}
+			return nil, fmt.Errorf("unsupported PKCS#8 key type %T", key)
+		}
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			return nil, fmt.Errorf("unsupported private key type %q", block.Type)
+		}
+	}
+}
+
+// checkKeyMatches returns an error unless keyData holds the private key of
+// the certificate
+func checkKeyMatches(keyData []byte, cert *x509.Certificate) error {
+	key, err := parsePrivateKey(keyData)
+	if err != nil {
+		return fmt.Errorf("invalid tls.key: %v", err)
+	}
+	if !publicKeyEqual(key, cert) {
+		return fmt.Errorf(keyMismatchError)
+	}
+	return nil
+}
+
+// publicKeyEqual reports whether key is the private key of the certificate
+func publicKeyEqual(key crypto.Signer, cert *x509.Certificate) bool {
+	public, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && public.Equal(cert.PublicKey)
+}
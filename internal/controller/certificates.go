@@ -2,41 +2,109 @@ package controller
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"sort"
 	"time"
 
 	certificatesv1 "k8s.io/api/certificates/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
 )
 
 type CertInfo struct {
-	Name          string
-	Namespace     string
+	// Kind is the kind of the object holding the certificate
+	Kind      string
+	Name      string
+	Namespace string
+	// FieldPath locates the certificate in the object, e.g. data[tls.crt]
+	FieldPath     string
+	Subject       string
+	Issuer        string
+	SANs          []string
 	NotBefore     time.Time
 	NotAfter      time.Time
 	DaysRemaining int
 	SerialNumber  string
 	IsExpired     bool
+	// Severity is computed from the expiry thresholds of the namespace
+	Severity Severity
+	// Chain holds every certificate of tls.crt, the leaf first
+	Chain []CertDetails
+	// ChainOrdered is false when a certificate of the chain is not issued
+	// by the one following it
+	ChainOrdered bool
+	// Validation is the result of checking tls.key and the chain
+	Validation CertValidation
+	// CertManagerCertificate is the cert-manager Certificate that owns the
+	// secret, empty for unmanaged secrets
+	CertManagerCertificate string
 }
 
 type CertController struct {
-	clientset *kubernetes.Clientset
+	clientset     kubernetes.Interface
+	dynamicClient dynamic.Interface
+	thresholds    ThresholdsFunc
 }
 
-func NewCertController(clientset *kubernetes.Clientset) *CertController {
+func NewCertController(clientset kubernetes.Interface, dynamicClient dynamic.Interface) *CertController {
 	return &CertController{
-		clientset: clientset,
+		clientset:     clientset,
+		dynamicClient: dynamicClient,
 	}
 }
 
+// IsTLSSecret reports whether the certificate is the tls.crt of a
+// kubernetes.io/tls secret, which can be validated and renewed
+func (i *CertInfo) IsTLSSecret() bool {
+	return i.Kind == kindSecret && i.FieldPath == tlsSecretFieldPath
+}
+
+// newCertInfo describes a certificate chain, the leaf first
+func (c *CertController) newCertInfo(kind, namespace, name, fieldPath string, chain []*x509.Certificate) CertInfo {
+	cert := chain[0]
+	info := CertInfo{
+		Kind:          kind,
+		Name:          name,
+		Namespace:     namespace,
+		FieldPath:     fieldPath,
+		Subject:       cert.Subject.String(),
+		Issuer:        cert.Issuer.String(),
+		NotBefore:     cert.NotBefore,
+		NotAfter:      cert.NotAfter,
+		DaysRemaining: int(time.Until(cert.NotAfter).Hours() / 24),
+		SerialNumber:  cert.SerialNumber.String(),
+		IsExpired:     time.Now().After(cert.NotAfter),
+		Severity:      c.severity(namespace, cert.NotAfter),
+		ChainOrdered:  chainOrdered(chain),
+	}
+	for _, c := range chain {
+		info.Chain = append(info.Chain, certDetails(c))
+	}
+	info.SANs = info.Chain[0].SANs()
+	return info
+}
+
+// Source names the object and field holding the certificate
+func (i *CertInfo) Source() string {
+	name := i.Name
+	if i.Namespace != "" {
+		name = i.Namespace + "/" + i.Name
+	}
+	if i.FieldPath == "" {
+		return i.Kind + " " + name
+	}
+	return i.Kind + " " + name + " " + i.FieldPath
+}
+
 // GetTLSCertificates retrieves all TLS certificates from secrets
 func (c *CertController) GetTLSCertificates() ([]CertInfo, error) {
 	secrets, err := c.clientset.CoreV1().Secrets("").List(context.TODO(), metav1.ListOptions{})
@@ -55,55 +123,47 @@ func (c *CertController) GetTLSCertificates() ([]CertInfo, error) {
 			continue
 		}
 
-		block, _ := pem.Decode(certData)
-		if block == nil {
-			continue
-		}
-
-		cert, err := x509.ParseCertificate(block.Bytes)
+		chain, err := parseCertificateChain(certData)
 		if err != nil {
 			continue
 		}
 
-		daysRemaining := int(time.Until(cert.NotAfter).Hours() / 24)
-
-		certInfos = append(certInfos, CertInfo{
-			Name:          secret.Name,
-			Namespace:     secret.Namespace,
-			NotBefore:     cert.NotBefore,
-			NotAfter:      cert.NotAfter,
-			DaysRemaining: daysRemaining,
-			SerialNumber:  cert.SerialNumber.String(),
-			IsExpired:     time.Now().After(cert.NotAfter),
-		})
+		info := c.newCertInfo(kindSecret, secret.Namespace, secret.Name, tlsSecretFieldPath, chain)
+		info.Validation = validateTLSSecret(&secret, chain, nil, "")
+		info.CertManagerCertificate = secret.Annotations[certificateNameAnnotation]
+		certInfos = append(certInfos, info)
 	}
 
+	// Soonest expiry first
+	sort.SliceStable(certInfos, func(i, j int) bool {
+		return certInfos[i].NotAfter.Before(certInfos[j].NotAfter)
+	})
 	return certInfos, nil
 }
 
-// RenewCertificate creates a new CSR and handles the renewal process
-func (c *CertController) RenewCertificate(namespace, name string) error {
-	secret, err := c.clientset.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
-	if err != nil {
+// RenewCertificate submits a CSR for the request, waits until it is
+// approved and issued, and stores the new certificate and key in the
+// secret. The CSR is only approved here when autoApprove is set, otherwise
+// an approver has to decide it. The CSR is deleted if the renewal fails or
+// is cancelled before the certificate is stored
+func (c *CertController) RenewCertificate(ctx context.Context, namespace, name string, req RenewalRequest, autoApprove bool, progress ProgressFunc) (err error) {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := c.clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
 		return fmt.Errorf("failed to get secret: %v", err)
 	}
 
 	// Generate new private key
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	progress.step("Generating %s key", req.KeyAlgorithm)
+	privateKey, err := generatePrivateKey(req.KeyAlgorithm, req.KeySize)
 	if err != nil {
 		return fmt.Errorf("failed to generate private key: %v", err)
 	}
 
-	// Create CSR template
-	template := &x509.CertificateRequest{
-		Subject: pkix.Name{
-			CommonName:   fmt.Sprintf("system:node:%s", name),
-			Organization: []string{"system:nodes"},
-		},
-	}
-
 	// Create CSR
-	csrDER, err := x509.CreateCertificateRequest(rand.Reader, template, privateKey)
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, req.certificateRequest(), privateKey)
 	if err != nil {
 		return fmt.Errorf("failed to create CSR: %v", err)
 	}
@@ -122,107 +182,110 @@ func (c *CertController) RenewCertificate(namespace, name string) error {
 		},
 		Spec: certificatesv1.CertificateSigningRequestSpec{
 			Request:           csrPEM,
-			SignerName:        "kubernetes.io/kube-apiserver-client",
-			ExpirationSeconds: int32Ptr(365 * 24 * 60 * 60), // 1 year
-			Usages: []certificatesv1.KeyUsage{
-				certificatesv1.UsageClientAuth,
-			},
+			SignerName:        req.SignerName,
+			ExpirationSeconds: int32Ptr(int32(req.Duration.Seconds())),
+			Usages:            req.Usages,
 		},
 	}
 
 	// Submit CSR
-	csr, err = c.clientset.CertificatesV1().CertificateSigningRequests().Create(context.TODO(), csr, metav1.CreateOptions{})
-	if err != nil {
+	progress.step("Submitting CSR %s to %s", csrName, req.SignerName)
+	csrs := c.clientset.CertificatesV1().CertificateSigningRequests()
+	if _, err := csrs.Create(ctx, csr, metav1.CreateOptions{}); err != nil {
 		return fmt.Errorf("failed to create CSR: %v", err)
 	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		// The key of the CSR is lost, so its certificate would be useless
+		cleanupCtx := context.WithoutCancel(ctx)
+		if delErr := csrs.Delete(cleanupCtx, csrName, metav1.DeleteOptions{}); delErr != nil {
+			err = fmt.Errorf("%v (also failed to delete CSR %s: %v)", err, csrName, delErr)
+		}
+	}()
 
-	// Approve CSR
-	approval := &certificatesv1.CertificateSigningRequest{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: csrName,
-		},
-		Status: certificatesv1.CertificateSigningRequestStatus{
-			Conditions: []certificatesv1.CertificateSigningRequestCondition{
-				{
-					Type:    certificatesv1.CertificateApproved,
-					Status:  corev1.ConditionTrue,
-					Reason:  "AutoApproved",
-					Message: "Automatically approved by certificate controller",
-				},
-			},
-		},
+	if autoApprove {
+		progress.step("Approving CSR %s (auto-approval is enabled)", csrName)
+		err := c.decideCSR(ctx, csrName, certificatesv1.CertificateApproved, csrAutoApprovedReason,
+			fmt.Sprintf("Renewal of secret %s/%s auto-approved by kubegreen", namespace, name))
+		if err != nil {
+			return err
+		}
 	}
 
-	_, err = c.clientset.CertificatesV1().CertificateSigningRequests().UpdateApproval(context.TODO(), csrName, approval, metav1.UpdateOptions{})
+	// Wait for certificate to be issued
+	certificate, err := c.waitForCSRCertificate(ctx, csrName, progress)
 	if err != nil {
-		return fmt.Errorf("failed to approve CSR: %v", err)
+		return err
 	}
-
-	// Wait for certificate to be issued
-	var certificate []byte
-	for i := 0; i < 10; i++ {
-		time.Sleep(time.Second)
-		csr, err = c.clientset.CertificatesV1().CertificateSigningRequests().Get(context.TODO(), csrName, metav1.GetOptions{})
-		if err != nil {
-			continue
-		}
-		if csr.Status.Certificate != nil {
-			certificate = csr.Status.Certificate
-			break
-		}
+	issued, err := parseCertificateChain(certificate)
+	if err != nil {
+		return fmt.Errorf("invalid certificate issued for CSR %s: %v", csrName, err)
 	}
-
-	if certificate == nil {
-		return fmt.Errorf("timeout waiting for certificate to be issued")
+	if !publicKeyEqual(privateKey, issued[0]) {
+		return fmt.Errorf("the certificate issued for CSR %s does not match the generated key", csrName)
 	}
 
 	// Update secret with new certificate
-	secret.Data["tls.crt"] = certificate
-	secret.Data["tls.key"] = encodePrivateKeyToPEM(privateKey)
-
-	_, err = c.clientset.CoreV1().Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	progress.step("Updating secret %s/%s", namespace, name)
+	keyPEM, err := encodePrivateKeyToPEM(privateKey)
+	if err != nil {
+		return err
+	}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := c.clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data["tls.crt"] = certificate
+		secret.Data["tls.key"] = keyPEM
+		_, err = c.clientset.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to update secret: %v", err)
 	}
 
+	progress.success("Renewed certificate %s/%s, valid until %s", namespace, name, issued[0].NotAfter.Format("2006-01-02 15:04"))
 	return nil
 }
 
-// GetKubeconfigCertInfo gets information about the current kubeconfig certificate
-func (c *CertController) GetKubeconfigCertInfo(certData string) (*CertInfo, error) {
-	decoded, err := base64.StdEncoding.DecodeString(certData)
+// GetKubeconfigCertInfo parses a PEM certificate of a kubeconfig, as found
+// in client-certificate-data or certificate-authority-data once decoded
+func (c *CertController) GetKubeconfigCertInfo(name string, certData []byte) (*CertInfo, error) {
+	chain, err := parseCertificateChain(certData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode certificate: %v", err)
+		return nil, fmt.Errorf("failed to parse certificate: %v", err)
 	}
+	info := c.newCertInfo("kubeconfig", "", name, "", chain)
+	return &info, nil
+}
 
-	block, _ := pem.Decode(decoded)
-	if block == nil {
-		return nil, fmt.Errorf("failed to parse certificate PEM")
+// Helper function to encode private key to PEM, in PKCS#1 form for RSA,
+// SEC 1 for ECDSA and PKCS#8 otherwise
+func encodePrivateKeyToPEM(privateKey crypto.Signer) ([]byte, error) {
+	switch k := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(k),
+		}), nil
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode private key: %v", err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
 	}
-
-	cert, err := x509.ParseCertificate(block.Bytes)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse certificate: %v", err)
+		return nil, fmt.Errorf("failed to encode private key: %v", err)
 	}
-
-	daysRemaining := int(time.Until(cert.NotAfter).Hours() / 24)
-
-	return &CertInfo{
-		Name:          "kubeconfig",
-		NotBefore:     cert.NotBefore,
-		NotAfter:      cert.NotAfter,
-		DaysRemaining: daysRemaining,
-		SerialNumber:  cert.SerialNumber.String(),
-		IsExpired:     time.Now().After(cert.NotAfter),
-	}, nil
-}
-
-// Helper function to encode private key to PEM
-func encodePrivateKeyToPEM(privateKey *rsa.PrivateKey) []byte {
-	return pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-	})
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
 }
 
 func int32Ptr(i int32) *int32 {
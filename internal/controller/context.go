@@ -3,7 +3,9 @@ package controller
 import (
 	"os"
 	"path/filepath"
+	"sort"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -11,16 +13,19 @@ import (
 )
 
 type ContextController struct {
-	clientset  *kubernetes.Clientset
-	config     *rest.Config
-	mclientset *metrics.Clientset
+	clientset     *kubernetes.Clientset
+	config        *rest.Config
+	mclientset    *metrics.Clientset
+	dynamicClient dynamic.Interface
 }
 
-func NewContextController() (*ContextController, error) {
-	home := os.Getenv("HOME")
-	kubeconfig := filepath.Join(home, ".kube", "config")
+// KubeconfigPath returns the location of the kubeconfig file
+func KubeconfigPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".kube", "config")
+}
 
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+func NewContextController() (*ContextController, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", KubeconfigPath())
 	if err != nil {
 		return nil, err
 	}
@@ -35,10 +40,16 @@ func NewContextController() (*ContextController, error) {
 		return nil, err
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return &ContextController{
-		clientset:  clientset,
-		config:     config,
-		mclientset: mclientset,
+		clientset:     clientset,
+		config:        config,
+		mclientset:    mclientset,
+		dynamicClient: dynamicClient,
 	}, nil
 }
 
@@ -54,8 +65,12 @@ func (c *ContextController) GetMetricsClientset() *metrics.Clientset {
 	return c.mclientset
 }
 
+func (c *ContextController) GetDynamicClient() dynamic.Interface {
+	return c.dynamicClient
+}
+
 func (c *ContextController) GetContexts() ([]string, error) {
-	config, err := clientcmd.LoadFromFile(filepath.Join(os.Getenv("HOME"), ".kube", "config"))
+	config, err := clientcmd.LoadFromFile(KubeconfigPath())
 	if err != nil {
 		return nil, err
 	}
@@ -64,11 +79,12 @@ func (c *ContextController) GetContexts() ([]string, error) {
 	for name := range config.Contexts {
 		contexts = append(contexts, name)
 	}
+	sort.Strings(contexts)
 	return contexts, nil
 }
 
 func (c *ContextController) SwitchContext(context string) error {
-	configPath := filepath.Join(os.Getenv("HOME"), ".kube", "config")
+	configPath := KubeconfigPath()
 	config, err := clientcmd.LoadFromFile(configPath)
 	if err != nil {
 		return err
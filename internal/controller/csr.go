@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	csrPollPeriod = 2 * time.Second
+
+	// Condition reasons set when a CSR is decided from kubegreen. The
+	// reason typed by the approver goes in the condition message
+	csrApprovedReason     = "KubegreenApproved"
+	csrDeniedReason       = "KubegreenDenied"
+	csrAutoApprovedReason = "KubegreenAutoApproved"
+)
+
+// CSR states derived from the conditions and the issued certificate
+const (
+	CSRPending  = "Pending"
+	CSRApproved = "Approved"
+	CSRIssued   = "Issued"
+	CSRDenied   = "Denied"
+	CSRFailed   = "Failed"
+)
+
+// CSRInfo describes a CertificateSigningRequest
+type CSRInfo struct {
+	Name       string
+	Requester  string
+	SignerName string
+	Usages     []string
+	Created    time.Time
+	Age        string
+	// State is one of CSRPending, CSRApproved, CSRIssued, CSRDenied and
+	// CSRFailed
+	State string
+	// Message is the message of the deciding condition
+	Message string
+}
+
+// ListCSRs returns all CertificateSigningRequests, pending ones first and
+// newest first within each group
+func (c *CertController) ListCSRs() ([]CSRInfo, error) {
+	list, err := c.clientset.CertificatesV1().CertificateSigningRequests().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certificate signing requests: %v", err)
+	}
+
+	var csrs []CSRInfo
+	for _, csr := range list.Items {
+		info := CSRInfo{
+			Name:       csr.Name,
+			Requester:  csr.Spec.Username,
+			SignerName: csr.Spec.SignerName,
+			Created:    csr.CreationTimestamp.Time,
+			Age:        formatAge(time.Since(csr.CreationTimestamp.Time)),
+		}
+		for _, usage := range csr.Spec.Usages {
+			info.Usages = append(info.Usages, string(usage))
+		}
+		info.State, info.Message = csrState(&csr)
+		csrs = append(csrs, info)
+	}
+
+	sort.SliceStable(csrs, func(i, j int) bool {
+		pi, pj := csrs[i].State == CSRPending, csrs[j].State == CSRPending
+		if pi != pj {
+			return pi
+		}
+		return csrs[i].Created.After(csrs[j].Created)
+	})
+	return csrs, nil
+}
+
+// csrState returns the state of a CSR and the message of the condition
+// that decided it
+func csrState(csr *certificatesv1.CertificateSigningRequest) (string, string) {
+	state, message := CSRPending, ""
+	for _, cond := range csr.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case certificatesv1.CertificateDenied:
+			return CSRDenied, cond.Message
+		case certificatesv1.CertificateFailed:
+			return CSRFailed, cond.Message
+		case certificatesv1.CertificateApproved:
+			state, message = CSRApproved, cond.Message
+		}
+	}
+	if state == CSRApproved && len(csr.Status.Certificate) > 0 {
+		state = CSRIssued
+	}
+	return state, message
+}
+
+// ApproveCSR approves a CertificateSigningRequest with the given reason
+func (c *CertController) ApproveCSR(name, reason string) error {
+	return c.decideCSR(context.TODO(), name, certificatesv1.CertificateApproved, csrApprovedReason, reason)
+}
+
+// DenyCSR denies a CertificateSigningRequest with the given reason
+func (c *CertController) DenyCSR(name, reason string) error {
+	return c.decideCSR(context.TODO(), name, certificatesv1.CertificateDenied, csrDeniedReason, reason)
+}
+
+func (c *CertController) decideCSR(ctx context.Context, name string, condition certificatesv1.RequestConditionType, reason, message string) error {
+	csrs := c.clientset.CertificatesV1().CertificateSigningRequests()
+	csr, err := csrs.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get CSR: %v", err)
+	}
+	if state, _ := csrState(csr); state != CSRPending {
+		return fmt.Errorf("CSR %s is already %s", name, state)
+	}
+
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:           condition,
+		Status:         corev1.ConditionTrue,
+		Reason:         reason,
+		Message:        message,
+		LastUpdateTime: metav1.Now(),
+	})
+	if _, err := csrs.UpdateApproval(ctx, name, csr, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update approval of CSR %s: %v", name, err)
+	}
+	return nil
+}
+
+// waitForCSRCertificate polls a CSR until its certificate is issued, and
+// fails once it is denied or failed
+func (c *CertController) waitForCSRCertificate(ctx context.Context, name string, progress ProgressFunc) ([]byte, error) {
+	lastState := ""
+	for {
+		csr, err := c.clientset.CertificatesV1().CertificateSigningRequests().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get CSR: %v", err)
+		}
+
+		state, message := csrState(csr)
+		switch state {
+		case CSRDenied, CSRFailed:
+			return nil, fmt.Errorf("CSR %s was %s: %s", name, state, orNone(message))
+		case CSRIssued:
+			return csr.Status.Certificate, nil
+		}
+		if state != lastState {
+			if state == CSRPending {
+				progress.step("Waiting for an approver (kubectl certificate approve %s)", name)
+			} else {
+				progress.step("Waiting for %s to issue the certificate", csr.Spec.SignerName)
+			}
+			lastState = state
+		}
+
+		if err := sleepContext(ctx, csrPollPeriod); err != nil {
+			return nil, err
+		}
+	}
+}
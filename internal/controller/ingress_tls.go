@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	probeTimeout = 5 * time.Second
+	httpsPort    = "443"
+)
+
+// IngressTLSEntry maps a tls entry of an Ingress to its secret
+type IngressTLSEntry struct {
+	Namespace  string
+	Ingress    string
+	Hosts      []string
+	SecretName string
+	// Cert is the certificate of the secret, nil when the secret is missing
+	// or holds no valid certificate
+	Cert *CertInfo
+	// Problems lists what is wrong with the secret, empty when it is usable
+	Problems []string
+	// UncoveredHosts are the hosts not matched by the SANs of Cert
+	UncoveredHosts []string
+}
+
+// OK reports whether the secret is usable and covers every host
+func (e *IngressTLSEntry) OK() bool {
+	return len(e.Problems) == 0 && len(e.UncoveredHosts) == 0
+}
+
+// EndpointProbe is the certificate served for a host of an Ingress
+type EndpointProbe struct {
+	Host    string
+	Address string
+	// Served is the leaf certificate returned by the endpoint, nil when
+	// the probe failed
+	Served *CertDetails
+	// Matches is set when Served is the certificate of the secret
+	Matches bool
+	Error   string
+}
+
+// GetIngressTLS lists the tls entries of every networking.k8s.io/v1 Ingress
+// with the certificate of their secret, flagging missing or invalid secrets
+// and hosts the certificate does not cover
+func (c *CertController) GetIngressTLS() ([]IngressTLSEntry, error) {
+	ctx := context.TODO()
+	ingresses, err := c.clientset.NetworkingV1().Ingresses("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ingresses: %v", err)
+	}
+
+	var entries []IngressTLSEntry
+	for _, ingress := range ingresses.Items {
+		for _, tlsEntry := range ingress.Spec.TLS {
+			entry := IngressTLSEntry{
+				Namespace:  ingress.Namespace,
+				Ingress:    ingress.Name,
+				Hosts:      tlsEntry.Hosts,
+				SecretName: tlsEntry.SecretName,
+			}
+			if err := c.resolveIngressSecret(ctx, &entry); err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Namespace != entries[j].Namespace {
+			return entries[i].Namespace < entries[j].Namespace
+		}
+		return entries[i].Ingress < entries[j].Ingress
+	})
+	return entries, nil
+}
+
+// resolveIngressSecret reads the secret of a tls entry and checks that its
+// certificate covers the hosts of the entry
+func (c *CertController) resolveIngressSecret(ctx context.Context, entry *IngressTLSEntry) error {
+	if entry.SecretName == "" {
+		entry.Problems = append(entry.Problems, "no secretName, the default certificate of the ingress controller is served")
+		return nil
+	}
+
+	secret, err := c.clientset.CoreV1().Secrets(entry.Namespace).Get(ctx, entry.SecretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		entry.Problems = append(entry.Problems, fmt.Sprintf("secret %s not found", entry.SecretName))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get secret %s/%s: %v", entry.Namespace, entry.SecretName, err)
+	}
+	if secret.Type != corev1.SecretTypeTLS {
+		entry.Problems = append(entry.Problems, fmt.Sprintf("secret %s is of type %s, not %s", secret.Name, secret.Type, corev1.SecretTypeTLS))
+	}
+	chain, err := parseCertificateChain(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		entry.Problems = append(entry.Problems, fmt.Sprintf("secret %s has no valid tls.crt: %v", secret.Name, err))
+		return nil
+	}
+
+	info := c.newCertInfo(kindSecret, secret.Namespace, secret.Name, tlsSecretFieldPath, chain)
+	info.Validation = validateTLSSecret(secret, chain, nil, "")
+	info.CertManagerCertificate = secret.Annotations[certificateNameAnnotation]
+	entry.Cert = &info
+	if info.Validation.KeyError != "" {
+		entry.Problems = append(entry.Problems, fmt.Sprintf("secret %s: %s", secret.Name, info.Validation.KeyError))
+	}
+	if info.IsExpired {
+		entry.Problems = append(entry.Problems, fmt.Sprintf("the certificate of secret %s has expired", secret.Name))
+	}
+	for _, host := range entry.Hosts {
+		if err := chain[0].VerifyHostname(host); err != nil {
+			entry.UncoveredHosts = append(entry.UncoveredHosts, host)
+		}
+	}
+	return nil
+}
+
+// ProbeCertificate opens a TLS connection to address, sending serverName
+// as SNI, and returns the leaf certificate served. The certificate is not
+// verified, so that untrusted and mismatched certificates can be compared
+func ProbeCertificate(ctx context.Context, address, serverName string) (*x509.Certificate, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	dialer := &tls.Dialer{
+		Config: &tls.Config{
+			ServerName:         serverName,
+			InsecureSkipVerify: true,
+		},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", address, err)
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s served no certificate", address)
+	}
+	return certs[0], nil
+}
+
+// ProbeIngressTLS probes every host of an entry and compares the served
+// certificate with the one of the secret. Hosts are dialed on port 443,
+// unless an address is given, e.g. the load balancer of the ingress
+// controller, in which case the host is only sent as SNI
+func ProbeIngressTLS(ctx context.Context, entry *IngressTLSEntry, address string, progress ProgressFunc) []EndpointProbe {
+	var probes []EndpointProbe
+	for _, host := range entry.Hosts {
+		probe := EndpointProbe{Host: host, Address: probeAddress(host, address)}
+		if strings.HasPrefix(host, "*") {
+			probe.Error = "wildcard hosts cannot be probed"
+			probes = append(probes, probe)
+			continue
+		}
+
+		progress.step("Probing %s at %s", host, probe.Address)
+		served, err := ProbeCertificate(ctx, probe.Address, host)
+		if err != nil {
+			probe.Error = err.Error()
+			progress.detail("%v", err)
+			probes = append(probes, probe)
+			continue
+		}
+		details := certDetails(served)
+		probe.Served = &details
+		if entry.Cert != nil && len(entry.Cert.Chain) > 0 {
+			probe.Matches = details.SHA256Fingerprint == entry.Cert.Chain[0].SHA256Fingerprint
+		}
+		if probe.Matches {
+			progress.success("%s serves the certificate of secret %s", host, entry.SecretName)
+		} else {
+			progress.detail("%s serves %s", host, details.Subject)
+		}
+		probes = append(probes, probe)
+	}
+	return probes
+}
+
+// probeAddress returns the address to dial for a host, defaulting the port
+// of the address to 443
+func probeAddress(host, address string) string {
+	if address == "" {
+		return net.JoinHostPort(host, httpsPort)
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return net.JoinHostPort(address, httpsPort)
+	}
+	return address
+}
@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"context"
+	"crypto"
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// servedSecret returns a TLS secret holding the certificate and key served
+// by an httptest TLS server
+func servedSecret(t *testing.T, server *httptest.Server, namespace, name string) *corev1.Secret {
+	t.Helper()
+	served := server.TLS.Certificates[0]
+	keyPEM, err := encodePrivateKeyToPEM(served.PrivateKey.(crypto.Signer))
+	if err != nil {
+		t.Fatalf("failed to encode the server key: %v", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: served.Certificate[0]}),
+			corev1.TLSPrivateKeyKey: keyPEM,
+		},
+	}
+}
+
+func newTestIngress(namespace, name string, tls ...networkingv1.IngressTLS) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       networkingv1.IngressSpec{TLS: tls},
+	}
+}
+
+func newTLSTestServer(t *testing.T) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	// Probes hang up after the handshake, which the server would log
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProbeCertificate(t *testing.T) {
+	server := newTLSTestServer(t)
+
+	served, err := ProbeCertificate(context.Background(), server.Listener.Addr().String(), "example.com")
+	if err != nil {
+		t.Fatalf("ProbeCertificate: %v", err)
+	}
+	if !served.Equal(server.Certificate()) {
+		t.Errorf("served certificate %s, want the certificate of the server", served.Subject)
+	}
+
+	// Nothing listens on a closed server
+	closed := httptest.NewTLSServer(http.NotFoundHandler())
+	address := closed.Listener.Addr().String()
+	closed.Close()
+	if _, err := ProbeCertificate(context.Background(), address, "example.com"); err == nil {
+		t.Error("ProbeCertificate of a closed address succeeded")
+	}
+}
+
+func TestGetIngressTLS(t *testing.T) {
+	ca := newTestCA(t, "test-ca")
+	valid := newTestServingCertificate(t, ca, 90*24*time.Hour, "app.example.com")
+	clientset := fake.NewSimpleClientset(
+		newTestTLSSecret("default", "app-tls", valid),
+		newTestIngress("default", "app",
+			networkingv1.IngressTLS{Hosts: []string{"app.example.com"}, SecretName: "app-tls"},
+			networkingv1.IngressTLS{Hosts: []string{"app.example.com", "api.example.com"}, SecretName: "app-tls"},
+			networkingv1.IngressTLS{Hosts: []string{"shop.example.com"}, SecretName: "shop-tls"},
+			networkingv1.IngressTLS{Hosts: []string{"default.example.com"}},
+		),
+	)
+	c := NewCertController(clientset, nil)
+
+	entries, err := c.GetIngressTLS()
+	if err != nil {
+		t.Fatalf("GetIngressTLS: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, want 4", len(entries))
+	}
+
+	if !entries[0].OK() || entries[0].Cert == nil {
+		t.Errorf("entry for a covered host: problems %v, uncovered %v, want OK", entries[0].Problems, entries[0].UncoveredHosts)
+	}
+	if len(entries[1].Problems) != 0 || len(entries[1].UncoveredHosts) != 1 || entries[1].UncoveredHosts[0] != "api.example.com" {
+		t.Errorf("entry for a host not in the SANs: problems %v, uncovered %v, want api.example.com uncovered",
+			entries[1].Problems, entries[1].UncoveredHosts)
+	}
+	if entries[2].Cert != nil || len(entries[2].Problems) != 1 || !strings.Contains(entries[2].Problems[0], "shop-tls not found") {
+		t.Errorf("entry for a missing secret: problems %v, want the secret reported missing", entries[2].Problems)
+	}
+	if len(entries[3].Problems) != 1 || !strings.Contains(entries[3].Problems[0], "no secretName") {
+		t.Errorf("entry without secret: problems %v, want no secretName reported", entries[3].Problems)
+	}
+}
+
+func TestProbeIngressTLS(t *testing.T) {
+	server := newTLSTestServer(t)
+	address := server.Listener.Addr().String()
+
+	ca := newTestCA(t, "test-ca")
+	other := newTestServingCertificate(t, ca, 90*24*time.Hour, "example.com")
+	clientset := fake.NewSimpleClientset(
+		servedSecret(t, server, "default", "served-tls"),
+		newTestTLSSecret("default", "other-tls", other),
+		newTestIngress("default", "matching", networkingv1.IngressTLS{Hosts: []string{"example.com"}, SecretName: "served-tls"}),
+		newTestIngress("default", "mismatched", networkingv1.IngressTLS{Hosts: []string{"example.com", "*.example.com"}, SecretName: "other-tls"}),
+	)
+	entries, err := NewCertController(clientset, nil).GetIngressTLS()
+	if err != nil {
+		t.Fatalf("GetIngressTLS: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	probes := ProbeIngressTLS(context.Background(), &entries[0], address, nil)
+	if len(probes) != 1 {
+		t.Fatalf("got %d probes, want 1", len(probes))
+	}
+	if probes[0].Error != "" || probes[0].Served == nil || !probes[0].Matches {
+		t.Errorf("probe of the served secret: %+v, want a matching fingerprint", probes[0])
+	}
+	if probes[0].Address != address {
+		t.Errorf("probe address %s, want %s", probes[0].Address, address)
+	}
+
+	probes = ProbeIngressTLS(context.Background(), &entries[1], address, nil)
+	if len(probes) != 2 {
+		t.Fatalf("got %d probes, want 2", len(probes))
+	}
+	if probes[0].Error != "" || probes[0].Served == nil || probes[0].Matches {
+		t.Errorf("probe of another secret: %+v, want a mismatched fingerprint", probes[0])
+	}
+	if probes[0].Served != nil && probes[0].Served.SHA256Fingerprint == entries[1].Cert.Chain[0].SHA256Fingerprint {
+		t.Error("served fingerprint equals the fingerprint of the secret")
+	}
+	if probes[1].Served != nil || probes[1].Error == "" {
+		t.Errorf("probe of a wildcard host: %+v, want it skipped", probes[1])
+	}
+}
+
+func TestProbeAddress(t *testing.T) {
+	tests := []struct {
+		host, address, want string
+	}{
+		{"app.example.com", "", "app.example.com:443"},
+		{"app.example.com", "10.0.0.1", "10.0.0.1:443"},
+		{"app.example.com", "10.0.0.1:8443", "10.0.0.1:8443"},
+	}
+	for _, tt := range tests {
+		if got := probeAddress(tt.host, tt.address); got != tt.want {
+			t.Errorf("probeAddress(%q, %q) = %q, want %q", tt.host, tt.address, got, tt.want)
+		}
+	}
+}
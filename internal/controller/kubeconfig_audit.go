@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// KubeconfigCertAudit holds the client certificate and cluster CA of a
+// kubeconfig context
+type KubeconfigCertAudit struct {
+	Context string
+	Cluster string
+	User    string
+
+	// ClientCert is nil when the user has no client certificate or it
+	// could not be read, see ClientCertNote
+	ClientCert       *CertInfo
+	ClientCertSource string
+	ClientCertNote   string
+
+	// CA is nil when the cluster has no CA or it could not be read, see
+	// CANote
+	CA       *CertInfo
+	CASource string
+	CANote   string
+}
+
+// Severity returns the worst severity of the client certificate and CA
+func (a *KubeconfigCertAudit) Severity() Severity {
+	severity := SeverityOK
+	for _, cert := range []*CertInfo{a.ClientCert, a.CA} {
+		if cert != nil && cert.Severity > severity {
+			severity = cert.Severity
+		}
+	}
+	return severity
+}
+
+// AuditKubeconfig checks the client certificate and cluster CA of every
+// context of the kubeconfig for expiry
+func (c *CertController) AuditKubeconfig() ([]KubeconfigCertAudit, error) {
+	path := KubeconfigPath()
+	config, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+	dir := filepath.Dir(path)
+
+	var audits []KubeconfigCertAudit
+	for name, context := range config.Contexts {
+		audit := KubeconfigCertAudit{
+			Context: name,
+			Cluster: context.Cluster,
+			User:    context.AuthInfo,
+		}
+
+		if user, ok := config.AuthInfos[context.AuthInfo]; !ok {
+			audit.ClientCertNote = fmt.Sprintf("user %s not found", context.AuthInfo)
+		} else {
+			audit.ClientCert, audit.ClientCertSource, audit.ClientCertNote = c.kubeconfigCert(
+				"client certificate", user.ClientCertificateData, user.ClientCertificate, dir)
+			if audit.ClientCertSource == "" && audit.ClientCertNote == "" {
+				audit.ClientCertNote = userAuthMethod(user)
+			}
+		}
+
+		if cluster, ok := config.Clusters[context.Cluster]; !ok {
+			audit.CANote = fmt.Sprintf("cluster %s not found", context.Cluster)
+		} else {
+			audit.CA, audit.CASource, audit.CANote = c.kubeconfigCert(
+				"cluster CA", cluster.CertificateAuthorityData, cluster.CertificateAuthority, dir)
+			if audit.CASource == "" && audit.CANote == "" {
+				audit.CANote = "no CA, the system roots are used"
+				if cluster.InsecureSkipTLSVerify {
+					audit.CANote = "TLS verification is disabled"
+				}
+			}
+		}
+		audits = append(audits, audit)
+	}
+
+	sort.Slice(audits, func(i, j int) bool {
+		return audits[i].Context < audits[j].Context
+	})
+	return audits, nil
+}
+
+// kubeconfigCert reads a certificate given inline or as a file path
+// relative to the kubeconfig directory. It returns the certificate, where
+// it was read from, and a note when it could not be read
+func (c *CertController) kubeconfigCert(name string, data []byte, file, dir string) (*CertInfo, string, string) {
+	source := "inline data"
+	if len(data) == 0 {
+		if file == "" {
+			return nil, "", ""
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		source = file
+		var err error
+		if data, err = os.ReadFile(file); err != nil {
+			return nil, source, fmt.Sprintf("failed to read %s: %v", name, err)
+		}
+	}
+
+	info, err := c.GetKubeconfigCertInfo(name, data)
+	if err != nil {
+		return nil, source, err.Error()
+	}
+	return info, source, ""
+}
+
+// userAuthMethod describes how a kubeconfig user without a client
+// certificate authenticates
+func userAuthMethod(user *clientcmdapi.AuthInfo) string {
+	switch {
+	case user.Exec != nil:
+		return fmt.Sprintf("no client certificate, authenticates with exec plugin %s", user.Exec.Command)
+	case user.AuthProvider != nil:
+		return fmt.Sprintf("no client certificate, authenticates with auth provider %s", user.AuthProvider.Name)
+	case user.Token != "" || user.TokenFile != "":
+		return "no client certificate, authenticates with a token"
+	case user.Username != "":
+		return "no client certificate, authenticates with basic auth"
+	}
+	return "no client certificate"
+}
@@ -49,6 +49,7 @@ type MetricsController struct {
 	mclientset      *metrics.Clientset
 	previousMetrics map[string]ContainerMetrics
 	podAges         map[string]time.Time
+	history         *metricsHistory
 }
 
 func NewMetricsController(clientset *kubernetes.Clientset, mclientset *metrics.Clientset) *MetricsController {
@@ -57,6 +58,7 @@ func NewMetricsController(clientset *kubernetes.Clientset, mclientset *metrics.C
 		mclientset:      mclientset,
 		previousMetrics: make(map[string]ContainerMetrics),
 		podAges:         make(map[string]time.Time),
+		history:         newMetricsHistory(DefaultMetricsWindow, defaultMetricsInterval),
 	}
 }
 
@@ -139,8 +141,9 @@ func (mc *MetricsController) GetFormattedMetrics(ctx context.Context) (*MetricsO
 		return nil, fmt.Errorf("error getting cluster capacity: %v", err)
 	}
 
+	now := time.Now()
 	output := &MetricsOutput{
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: now.Format(time.RFC3339),
 		System:    sysMetrics,
 		Pods:      make([]PodMetrics, 0),
 	}
@@ -224,6 +227,7 @@ func (mc *MetricsController) GetFormattedMetrics(ctx context.Context) (*MetricsO
 	for _, podMetrics := range podMap {
 		output.Pods = append(output.Pods, *podMetrics)
 	}
+	mc.recordHistory(now, output.Pods)
 
 	return output, nil
 }
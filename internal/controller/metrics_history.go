@@ -0,0 +1,224 @@
+package controller
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMetricsWindow is the span of metrics history kept when none is
+	// set
+	DefaultMetricsWindow   = 30 * time.Minute
+	defaultMetricsInterval = 2 * time.Second
+)
+
+// MetricSample is the usage of a container or pod at one collection
+type MetricSample struct {
+	Time   time.Time
+	CPU    int64 // millicores
+	Memory int64 // MB
+}
+
+// SeriesStats summarizes the samples of a series
+type SeriesStats struct {
+	Min int64
+	Avg float64
+	Max int64
+	P95 int64
+}
+
+// MetricsSeries is the history of a pod, or of one of its containers
+type MetricsSeries struct {
+	Namespace string
+	Pod       string
+	// Container is empty for the series of the whole pod
+	Container string
+	// Samples are ordered oldest first
+	Samples []MetricSample
+	CPU     SeriesStats
+	Memory  SeriesStats
+}
+
+// Latest returns the most recent sample
+func (s *MetricsSeries) Latest() MetricSample {
+	if len(s.Samples) == 0 {
+		return MetricSample{}
+	}
+	return s.Samples[len(s.Samples)-1]
+}
+
+// CPUValues returns the CPU of the samples, oldest first
+func (s *MetricsSeries) CPUValues() []int64 {
+	values := make([]int64, len(s.Samples))
+	for i, sample := range s.Samples {
+		values[i] = sample.CPU
+	}
+	return values
+}
+
+// MemoryValues returns the memory of the samples, oldest first
+func (s *MetricsSeries) MemoryValues() []int64 {
+	values := make([]int64, len(s.Samples))
+	for i, sample := range s.Samples {
+		values[i] = sample.Memory
+	}
+	return values
+}
+
+// sampleRing keeps the last samples of a series in a fixed-size buffer
+type sampleRing struct {
+	samples []MetricSample
+	start   int
+	count   int
+}
+
+func newSampleRing(capacity int) *sampleRing {
+	return &sampleRing{samples: make([]MetricSample, capacity)}
+}
+
+func (r *sampleRing) add(sample MetricSample) {
+	if r.count < len(r.samples) {
+		r.samples[(r.start+r.count)%len(r.samples)] = sample
+		r.count++
+		return
+	}
+	// Full, overwrite the oldest sample
+	r.samples[r.start] = sample
+	r.start = (r.start + 1) % len(r.samples)
+}
+
+// values returns the samples oldest first, dropping those older than since
+func (r *sampleRing) values(since time.Time) []MetricSample {
+	values := make([]MetricSample, 0, r.count)
+	for i := 0; i < r.count; i++ {
+		sample := r.samples[(r.start+i)%len(r.samples)]
+		if !sample.Time.Before(since) {
+			values = append(values, sample)
+		}
+	}
+	return values
+}
+
+func (r *sampleRing) last() MetricSample {
+	return r.samples[(r.start+r.count-1)%len(r.samples)]
+}
+
+// metricsHistory holds a ring of samples per pod and per container, sized
+// to cover the window at the collection interval
+type metricsHistory struct {
+	window   time.Duration
+	capacity int
+	series   map[string]*sampleRing
+}
+
+func newMetricsHistory(window, interval time.Duration) *metricsHistory {
+	capacity := 1
+	if interval > 0 {
+		capacity = int(window/interval) + 1
+	}
+	return &metricsHistory{
+		window:   window,
+		capacity: capacity,
+		series:   make(map[string]*sampleRing),
+	}
+}
+
+func (h *metricsHistory) record(key string, sample MetricSample) {
+	ring, ok := h.series[key]
+	if !ok {
+		ring = newSampleRing(h.capacity)
+		h.series[key] = ring
+	}
+	ring.add(sample)
+}
+
+// prune drops the series of pods and containers not seen within the window
+func (h *metricsHistory) prune(now time.Time) {
+	for key, ring := range h.series {
+		if now.Sub(ring.last().Time) > h.window {
+			delete(h.series, key)
+		}
+	}
+}
+
+// SetHistoryWindow sets the span of metrics history kept for each pod and
+// container, sampled every interval. The current history is dropped
+func (mc *MetricsController) SetHistoryWindow(window, interval time.Duration) {
+	mc.history = newMetricsHistory(window, interval)
+}
+
+// recordHistory adds the samples of a collection to the history: one per
+// container, keyed namespace/pod/container, and one per pod summing its
+// containers, keyed namespace/pod
+func (mc *MetricsController) recordHistory(now time.Time, pods []PodMetrics) {
+	for _, pod := range pods {
+		total := MetricSample{Time: now}
+		for name, container := range pod.Containers {
+			mc.history.record(getMetricKey(pod.Namespace, pod.Name, name), MetricSample{
+				Time:   now,
+				CPU:    container.CPU,
+				Memory: container.Memory,
+			})
+			total.CPU += container.CPU
+			total.Memory += container.Memory
+		}
+		mc.history.record(getPodKey(pod.Namespace, pod.Name), total)
+	}
+	mc.history.prune(now)
+}
+
+// History returns the series of every pod and container seen within the
+// window, with their statistics, ordered by key
+func (mc *MetricsController) History() []MetricsSeries {
+	since := time.Now().Add(-mc.history.window)
+	var series []MetricsSeries
+	for key, ring := range mc.history.series {
+		samples := ring.values(since)
+		if len(samples) == 0 {
+			continue
+		}
+		parts := strings.SplitN(key, "/", 3)
+		s := MetricsSeries{Namespace: parts[0], Pod: parts[1], Samples: samples}
+		if len(parts) == 3 {
+			s.Container = parts[2]
+		}
+		s.CPU = seriesStats(s.CPUValues())
+		s.Memory = seriesStats(s.MemoryValues())
+		series = append(series, s)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		a, b := &series[i], &series[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Pod != b.Pod {
+			return a.Pod < b.Pod
+		}
+		return a.Container < b.Container
+	})
+	return series
+}
+
+// seriesStats returns the min, average, max and nearest-rank 95th
+// percentile of values
+func seriesStats(values []int64) SeriesStats {
+	if len(values) == 0 {
+		return SeriesStats{}
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum int64
+	for _, v := range sorted {
+		sum += v
+	}
+	rank := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+	return SeriesStats{
+		Min: sorted[0],
+		Avg: float64(sum) / float64(len(sorted)),
+		Max: sorted[len(sorted)-1],
+		P95: sorted[rank],
+	}
+}
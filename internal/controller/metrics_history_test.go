@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSampleRing(t *testing.T) {
+	start := time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC)
+	at := func(i int) time.Time { return start.Add(time.Duration(i) * time.Second) }
+
+	tests := []struct {
+		name     string
+		capacity int
+		added    int
+		since    time.Time
+		want     []int64
+	}{
+		{"empty", 3, 0, time.Time{}, []int64{}},
+		{"partially filled", 3, 2, time.Time{}, []int64{1, 2}},
+		{"full", 3, 3, time.Time{}, []int64{1, 2, 3}},
+		{"wrapped around", 3, 5, time.Time{}, []int64{3, 4, 5}},
+		{"wrapped around twice", 3, 7, time.Time{}, []int64{5, 6, 7}},
+		{"older samples dropped", 3, 5, at(4), []int64{4, 5}},
+		{"every sample too old", 3, 5, at(6), []int64{}},
+		{"single sample", 1, 4, time.Time{}, []int64{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring := newSampleRing(tt.capacity)
+			for i := 1; i <= tt.added; i++ {
+				ring.add(MetricSample{Time: at(i), CPU: int64(i)})
+			}
+
+			got := []int64{}
+			for _, sample := range ring.values(tt.since) {
+				got = append(got, sample.CPU)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("values = %v, want %v", got, tt.want)
+			}
+			if tt.added > 0 && ring.last().CPU != int64(tt.added) {
+				t.Errorf("last = %d, want %d", ring.last().CPU, tt.added)
+			}
+		})
+	}
+}
+
+func TestSeriesStats(t *testing.T) {
+	oneToTwenty := make([]int64, 20)
+	for i := range oneToTwenty {
+		oneToTwenty[i] = int64(i + 1)
+	}
+	tests := []struct {
+		name   string
+		values []int64
+		want   SeriesStats
+	}{
+		{"empty", nil, SeriesStats{}},
+		{"single value", []int64{10}, SeriesStats{Min: 10, Avg: 10, Max: 10, P95: 10}},
+		{"unsorted", []int64{5, 1, 3}, SeriesStats{Min: 1, Avg: 3, Max: 5, P95: 5}},
+		// The 95th percentile of 20 values is the 19th
+		{"nearest rank", oneToTwenty, SeriesStats{Min: 1, Avg: 10.5, Max: 20, P95: 19}},
+	}
+	for _, tt := range tests {
+		if got := seriesStats(tt.values); got != tt.want {
+			t.Errorf("%s: stats = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMetricsHistory(t *testing.T) {
+	if got := newMetricsHistory(DefaultMetricsWindow, defaultMetricsInterval).capacity; got != 901 {
+		t.Errorf("capacity = %d, want a sample every 2s over 30m and the current one", got)
+	}
+	if got := newMetricsHistory(DefaultMetricsWindow, 0).capacity; got != 1 {
+		t.Errorf("capacity without interval = %d, want 1", got)
+	}
+
+	now := time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC)
+	h := newMetricsHistory(30*time.Minute, time.Minute)
+	h.record("default/gone", MetricSample{Time: now.Add(-31 * time.Minute)})
+	h.record("default/idle", MetricSample{Time: now.Add(-30 * time.Minute)})
+	h.record("default/web", MetricSample{Time: now.Add(-40 * time.Minute)})
+	h.record("default/web", MetricSample{Time: now})
+	h.prune(now)
+
+	var kept []string
+	for _, key := range []string{"default/gone", "default/idle", "default/web"} {
+		if _, ok := h.series[key]; ok {
+			kept = append(kept, key)
+		}
+	}
+	if want := []string{"default/idle", "default/web"}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept %v after pruning, want %v", kept, want)
+	}
+}
@@ -0,0 +1,335 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	notifyTimeout = 10 * time.Second
+
+	// DefaultAlertTemplate renders the message of an expiring certificate
+	DefaultAlertTemplate = `[{{.Severity}}] {{.Source}} {{if .Expired}}expired on{{else}}expires on{{end}} {{.NotAfter.Format "2006-01-02"}} ({{.DaysRemaining}} days)`
+)
+
+// WebhookFormat is the payload shape posted to a webhook
+type WebhookFormat string
+
+const (
+	// WebhookJSON posts the alerts as JSON objects along with their messages
+	WebhookJSON WebhookFormat = "json"
+	// WebhookSlack posts a Slack incoming webhook message
+	WebhookSlack WebhookFormat = "slack"
+	// WebhookTeams posts a Microsoft Teams connector card
+	WebhookTeams WebhookFormat = "teams"
+)
+
+// Webhook is a notification target
+type Webhook struct {
+	Name   string
+	URL    string
+	Format WebhookFormat
+	// MinSeverity is the least severe certificate status notified
+	MinSeverity Severity
+	template    *template.Template
+}
+
+// NewWebhook checks the settings of a webhook. An empty format posts
+// generic JSON, an empty template uses DefaultAlertTemplate and an empty
+// minimum severity notifies warning certificates and above
+func NewWebhook(name, url, format, messageTemplate, minSeverity string) (*Webhook, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook %s has no url", name)
+	}
+	if name == "" {
+		name = url
+	}
+	webhook := &Webhook{Name: name, URL: url, Format: WebhookFormat(strings.ToLower(format)), MinSeverity: SeverityWarning}
+	switch webhook.Format {
+	case "":
+		webhook.Format = WebhookJSON
+	case WebhookJSON, WebhookSlack, WebhookTeams:
+	default:
+		return nil, fmt.Errorf("webhook %s: unknown format %q, expected json, slack or teams", name, format)
+	}
+	if minSeverity != "" {
+		severity, err := ParseSeverity(minSeverity)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %s: %v", name, err)
+		}
+		webhook.MinSeverity = severity
+	}
+	if messageTemplate == "" {
+		messageTemplate = DefaultAlertTemplate
+	}
+	tmpl, err := template.New(name).Parse(messageTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("webhook %s: invalid template: %v", name, err)
+	}
+	webhook.template = tmpl
+	return webhook, nil
+}
+
+// ParseSeverity returns the severity of a name, ignoring case
+func ParseSeverity(name string) (Severity, error) {
+	for _, severity := range Severities {
+		if strings.EqualFold(name, severity.String()) {
+			return severity, nil
+		}
+	}
+	return SeverityOK, fmt.Errorf("unknown severity %q, expected ok, warning, critical or expired", name)
+}
+
+// CertAlert is an expiring certificate as passed to message templates and
+// posted in JSON payloads
+type CertAlert struct {
+	Namespace     string    `json:"namespace"`
+	Kind          string    `json:"kind"`
+	Name          string    `json:"name"`
+	FieldPath     string    `json:"fieldPath"`
+	Source        string    `json:"source"`
+	Subject       string    `json:"subject"`
+	Issuer        string    `json:"issuer"`
+	SerialNumber  string    `json:"serialNumber"`
+	NotAfter      time.Time `json:"notAfter"`
+	DaysRemaining int       `json:"daysRemaining"`
+	Severity      string    `json:"severity"`
+	Expired       bool      `json:"expired"`
+	Message       string    `json:"message"`
+}
+
+func newCertAlert(cert *CertInfo) CertAlert {
+	return CertAlert{
+		Namespace:     cert.Namespace,
+		Kind:          cert.Kind,
+		Name:          cert.Name,
+		FieldPath:     cert.FieldPath,
+		Source:        cert.Source(),
+		Subject:       cert.Subject,
+		Issuer:        cert.Issuer,
+		SerialNumber:  cert.SerialNumber,
+		NotAfter:      cert.NotAfter,
+		DaysRemaining: cert.DaysRemaining,
+		Severity:      cert.Severity.String(),
+		Expired:       cert.IsExpired,
+	}
+}
+
+// alertKey identifies a certificate in the notification state
+func alertKey(cert *CertInfo) string {
+	return cert.Source() + "#" + cert.SerialNumber
+}
+
+// NotificationState records the day each certificate was last notified to
+// each webhook, to send at most one alert per certificate per day
+type NotificationState struct {
+	// Sent maps a webhook name to the day, as 2006-01-02, each
+	// certificate was last notified
+	Sent map[string]map[string]string `json:"sent"`
+}
+
+// Notifier posts alerts about expiring certificates to webhooks
+type Notifier struct {
+	webhooks  []*Webhook
+	statePath string
+	client    *http.Client
+	now       func() time.Time
+}
+
+// NewNotifier returns a notifier recording the alerts sent in statePath
+func NewNotifier(webhooks []*Webhook, statePath string) *Notifier {
+	return &Notifier{
+		webhooks:  webhooks,
+		statePath: statePath,
+		client:    &http.Client{Timeout: notifyTimeout},
+		now:       time.Now,
+	}
+}
+
+// NotifyResult counts the alerts of a notification run
+type NotifyResult struct {
+	// Sent counts the alerts posted to each webhook
+	Sent map[string]int
+	// Deduplicated counts the alerts already sent today
+	Deduplicated int
+}
+
+// Notify posts the certificates at or above the minimum severity of each
+// webhook, skipping those already notified to it today. Each webhook gets a
+// single request. A failed webhook does not stop the others, and its
+// alerts are sent again on the next run
+func (n *Notifier) Notify(ctx context.Context, certs []CertInfo) (*NotifyResult, error) {
+	state, err := n.loadState()
+	if err != nil {
+		return nil, err
+	}
+	today := n.now().Format("2006-01-02")
+	result := &NotifyResult{Sent: make(map[string]int)}
+	certs = sortForExport(certs)
+
+	var failed []string
+webhooks:
+	for _, webhook := range n.webhooks {
+		sent := state.Sent[webhook.Name]
+		var alerts []CertAlert
+		var keys []string
+		for i := range certs {
+			cert := &certs[i]
+			if cert.Severity < webhook.MinSeverity {
+				continue
+			}
+			key := alertKey(cert)
+			if sent[key] == today {
+				result.Deduplicated++
+				continue
+			}
+			alert := newCertAlert(cert)
+			var message bytes.Buffer
+			if err := webhook.template.Execute(&message, alert); err != nil {
+				// The other webhooks are still notified
+				failed = append(failed, fmt.Sprintf("%s: failed to render the message: %v", webhook.Name, err))
+				continue webhooks
+			}
+			alert.Message = message.String()
+			alerts = append(alerts, alert)
+			keys = append(keys, key)
+		}
+		if len(alerts) == 0 {
+			continue
+		}
+
+		if err := n.post(ctx, webhook, alerts); err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		if sent == nil {
+			sent = make(map[string]string)
+			state.Sent[webhook.Name] = sent
+		}
+		for _, key := range keys {
+			sent[key] = today
+		}
+		result.Sent[webhook.Name] = len(alerts)
+	}
+
+	if err := n.saveState(state, today); err != nil {
+		return result, err
+	}
+	if len(failed) > 0 {
+		return result, fmt.Errorf("failed to notify %s", strings.Join(failed, "; "))
+	}
+	return result, nil
+}
+
+// post sends the alerts to a webhook in its payload shape
+func (n *Notifier) post(ctx context.Context, webhook *Webhook, alerts []CertAlert) error {
+	payload, err := webhookPayload(webhook.Format, alerts)
+	if err != nil {
+		return fmt.Errorf("%s: %v", webhook.Name, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("%s: %v", webhook.Name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %v", webhook.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s %s", webhook.Name, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func webhookPayload(format WebhookFormat, alerts []CertAlert) ([]byte, error) {
+	messages := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		messages = append(messages, alert.Message)
+	}
+	title := fmt.Sprintf("%d certificates need attention", len(alerts))
+	if len(alerts) == 1 {
+		title = "1 certificate needs attention"
+	}
+
+	switch format {
+	case WebhookSlack:
+		return json.Marshal(map[string]interface{}{
+			"text": title + "\n" + strings.Join(messages, "\n"),
+		})
+	case WebhookTeams:
+		return json.Marshal(map[string]interface{}{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  title,
+			"title":    title,
+			// Teams renders text as Markdown, paragraphs need blank lines
+			"text": strings.Join(messages, "\n\n"),
+		})
+	}
+	return json.Marshal(map[string]interface{}{
+		"title":  title,
+		"alerts": alerts,
+	})
+}
+
+func (n *Notifier) loadState() (*NotificationState, error) {
+	state := &NotificationState{Sent: make(map[string]map[string]string)}
+	data, err := os.ReadFile(n.statePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notification state: %v", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse notification state %s: %v", n.statePath, err)
+	}
+	if state.Sent == nil {
+		state.Sent = make(map[string]map[string]string)
+	}
+	return state, nil
+}
+
+// saveState writes the state, keeping only the alerts sent today
+func (n *Notifier) saveState(state *NotificationState, today string) error {
+	for webhook, sent := range state.Sent {
+		for key, day := range sent {
+			if day != today {
+				delete(sent, key)
+			}
+		}
+		if len(sent) == 0 {
+			delete(state.Sent, webhook)
+		}
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode notification state: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(n.statePath), 0o755); err != nil {
+		return fmt.Errorf("failed to create notification state directory: %v", err)
+	}
+	// Write then rename, so that an interrupted run keeps the previous state
+	tmp := n.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write notification state: %v", err)
+	}
+	if err := os.Rename(tmp, n.statePath); err != nil {
+		return fmt.Errorf("failed to write notification state: %v", err)
+	}
+	return nil
+}
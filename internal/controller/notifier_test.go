@@ -0,0 +1,336 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookRecorder is a local stand-in for a webhook, recording the payloads
+// posted to it
+type webhookRecorder struct {
+	*httptest.Server
+	mu       sync.Mutex
+	payloads []map[string]interface{}
+	status   int
+}
+
+func newWebhookRecorder(t *testing.T) *webhookRecorder {
+	r := &webhookRecorder{status: http.StatusOK}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		var payload map[string]interface{}
+		if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" || json.Unmarshal(body, &payload) != nil {
+			t.Errorf("unexpected request %s %s: %s", req.Method, req.Header.Get("Content-Type"), body)
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.payloads = append(r.payloads, payload)
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *webhookRecorder) received() []map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]map[string]interface{}(nil), r.payloads...)
+}
+
+func newTestAlertCert(name string, severity Severity, days int) CertInfo {
+	return CertInfo{
+		Namespace:     "default",
+		Kind:          kindSecret,
+		Name:          name,
+		FieldPath:     tlsSecretFieldPath,
+		Subject:       "CN=" + name,
+		SerialNumber:  name + "-serial",
+		NotAfter:      time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, days),
+		DaysRemaining: days,
+		Severity:      severity,
+		IsExpired:     severity == SeverityExpired,
+	}
+}
+
+func testAlertCerts() []CertInfo {
+	return []CertInfo{
+		newTestAlertCert("fine", SeverityOK, 200),
+		newTestAlertCert("soon", SeverityWarning, 20),
+		newTestAlertCert("urgent", SeverityCritical, 3),
+		newTestAlertCert("gone", SeverityExpired, -2),
+	}
+}
+
+func newTestNotifier(t *testing.T, day time.Time, webhooks ...*Webhook) *Notifier {
+	n := NewNotifier(webhooks, filepath.Join(t.TempDir(), "notifications.json"))
+	n.now = func() time.Time { return day }
+	return n
+}
+
+func mustNewWebhook(t *testing.T, name, url, format, messageTemplate, minSeverity string) *Webhook {
+	t.Helper()
+	webhook, err := NewWebhook(name, url, format, messageTemplate, minSeverity)
+	if err != nil {
+		t.Fatalf("NewWebhook: %v", err)
+	}
+	return webhook
+}
+
+func TestNewWebhook(t *testing.T) {
+	webhook := mustNewWebhook(t, "", "http://example.com/hook", "", "", "")
+	if webhook.Name != "http://example.com/hook" || webhook.Format != WebhookJSON || webhook.MinSeverity != SeverityWarning {
+		t.Errorf("defaults = %+v, want json posted for warning and above", webhook)
+	}
+
+	invalid := []struct {
+		name                                   string
+		url, format, messageTemplate, severity string
+	}{
+		{"no url", "", "", "", ""},
+		{"unknown format", "http://example.com/hook", "email", "", ""},
+		{"unknown severity", "http://example.com/hook", "", "", "urgent"},
+		{"invalid template", "http://example.com/hook", "", "{{.Name", ""},
+	}
+	for _, tt := range invalid {
+		if _, err := NewWebhook("hook", tt.url, tt.format, tt.messageTemplate, tt.severity); err == nil {
+			t.Errorf("%s: NewWebhook succeeded", tt.name)
+		}
+	}
+}
+
+func TestNotifyPayloads(t *testing.T) {
+	day := time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC)
+	jsonHook, slackHook, teamsHook := newWebhookRecorder(t), newWebhookRecorder(t), newWebhookRecorder(t)
+	n := newTestNotifier(t, day,
+		mustNewWebhook(t, "json", jsonHook.URL, "json", "", "critical"),
+		mustNewWebhook(t, "slack", slackHook.URL, "slack", "", "critical"),
+		mustNewWebhook(t, "teams", teamsHook.URL, "teams", "", "critical"),
+	)
+
+	result, err := n.Notify(context.Background(), testAlertCerts())
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	for _, name := range []string{"json", "slack", "teams"} {
+		if result.Sent[name] != 2 {
+			t.Errorf("%s: %d alerts sent, want 2", name, result.Sent[name])
+		}
+	}
+
+	urgent := "[Critical] Secret default/urgent data[tls.crt] expires on 2026-03-04 (3 days)"
+	gone := "[Expired] Secret default/gone data[tls.crt] expired on 2026-02-27 (-2 days)"
+
+	payloads := jsonHook.received()
+	if len(payloads) != 1 {
+		t.Fatalf("json: %d requests, want 1", len(payloads))
+	}
+	alerts, _ := payloads[0]["alerts"].([]interface{})
+	if payloads[0]["title"] != "2 certificates need attention" || len(alerts) != 2 {
+		t.Fatalf("json payload = %v, want 2 alerts", payloads[0])
+	}
+	first := alerts[0].(map[string]interface{})
+	if first["name"] != "gone" || first["severity"] != "Expired" || first["expired"] != true || first["message"] != gone {
+		t.Errorf("json alert = %v, want the expired certificate gone", first)
+	}
+
+	payloads = slackHook.received()
+	if len(payloads) != 1 {
+		t.Fatalf("slack: %d requests, want 1", len(payloads))
+	}
+	if want := "2 certificates need attention\n" + gone + "\n" + urgent; payloads[0]["text"] != want {
+		t.Errorf("slack text = %q, want %q", payloads[0]["text"], want)
+	}
+
+	payloads = teamsHook.received()
+	if len(payloads) != 1 {
+		t.Fatalf("teams: %d requests, want 1", len(payloads))
+	}
+	card := payloads[0]
+	if card["@type"] != "MessageCard" || card["title"] != "2 certificates need attention" || card["text"] != gone+"\n\n"+urgent {
+		t.Errorf("teams card = %v, want a MessageCard with a paragraph per alert", card)
+	}
+}
+
+func TestNotifyTemplate(t *testing.T) {
+	hook := newWebhookRecorder(t)
+	n := newTestNotifier(t, time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC),
+		mustNewWebhook(t, "ops", hook.URL, "slack", "{{.Name}} in {{.Namespace}}: {{.DaysRemaining}}d, {{.Subject}}", "warning"))
+
+	if _, err := n.Notify(context.Background(), testAlertCerts()[1:2]); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	payloads := hook.received()
+	if len(payloads) != 1 {
+		t.Fatalf("%d requests, want 1", len(payloads))
+	}
+	if want := "1 certificate needs attention\nsoon in default: 20d, CN=soon"; payloads[0]["text"] != want {
+		t.Errorf("text = %q, want %q", payloads[0]["text"], want)
+	}
+}
+
+func TestNotifyMinSeverity(t *testing.T) {
+	tests := []struct {
+		minSeverity string
+		want        []string
+	}{
+		{"", []string{"gone", "soon", "urgent"}},
+		{"warning", []string{"gone", "soon", "urgent"}},
+		{"critical", []string{"gone", "urgent"}},
+		{"expired", []string{"gone"}},
+	}
+	for _, tt := range tests {
+		hook := newWebhookRecorder(t)
+		n := newTestNotifier(t, time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC),
+			mustNewWebhook(t, "ops", hook.URL, "json", "{{.Name}}", tt.minSeverity))
+		if _, err := n.Notify(context.Background(), testAlertCerts()); err != nil {
+			t.Fatalf("%s: Notify: %v", tt.minSeverity, err)
+		}
+
+		payloads := hook.received()
+		if len(payloads) != 1 {
+			t.Fatalf("%s: %d requests, want 1", tt.minSeverity, len(payloads))
+		}
+		var names []string
+		for _, alert := range payloads[0]["alerts"].([]interface{}) {
+			names = append(names, alert.(map[string]interface{})["message"].(string))
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("minSeverity %q notified %v, want %v", tt.minSeverity, names, tt.want)
+		}
+	}
+}
+
+func TestNotifyNothingToSend(t *testing.T) {
+	hook := newWebhookRecorder(t)
+	n := newTestNotifier(t, time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC), mustNewWebhook(t, "ops", hook.URL, "", "", ""))
+
+	if _, err := n.Notify(context.Background(), testAlertCerts()[:1]); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if payloads := hook.received(); len(payloads) != 0 {
+		t.Errorf("%d requests for healthy certificates, want none", len(payloads))
+	}
+}
+
+func TestNotifyDeduplicatesPerDay(t *testing.T) {
+	hook := newWebhookRecorder(t)
+	day := time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC)
+	n := newTestNotifier(t, day, mustNewWebhook(t, "ops", hook.URL, "slack", "", "critical"))
+	certs := testAlertCerts()
+
+	result, err := n.Notify(context.Background(), certs)
+	if err != nil || result.Sent["ops"] != 2 {
+		t.Fatalf("first run: sent %v, err %v, want 2 alerts", result, err)
+	}
+
+	// Later the same day the alerts are suppressed
+	day = day.Add(10 * time.Hour)
+	n.now = func() time.Time { return day }
+	result, err = n.Notify(context.Background(), certs)
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if result.Sent["ops"] != 0 || result.Deduplicated != 2 || len(hook.received()) != 1 {
+		t.Errorf("second run: sent %d, deduplicated %d, %d requests; want everything suppressed",
+			result.Sent["ops"], result.Deduplicated, len(hook.received()))
+	}
+
+	// A certificate crossing the threshold the same day is still sent
+	certs[1].Severity = SeverityCritical
+	result, err = n.Notify(context.Background(), certs)
+	if err != nil || result.Sent["ops"] != 1 || result.Deduplicated != 2 {
+		t.Errorf("third run: sent %v, err %v, want only the new critical certificate", result, err)
+	}
+
+	// The next day every alert is sent again
+	day = day.Add(24 * time.Hour)
+	n.now = func() time.Time { return day }
+	result, err = n.Notify(context.Background(), certs)
+	if err != nil || result.Sent["ops"] != 3 || result.Deduplicated != 0 {
+		t.Errorf("next day: sent %v, err %v, want 3 alerts", result, err)
+	}
+	if len(hook.received()) != 3 {
+		t.Errorf("%d requests, want 3", len(hook.received()))
+	}
+
+	// The state file only keeps the alerts of the current day
+	data, err := os.ReadFile(n.statePath)
+	if err != nil {
+		t.Fatalf("state file: %v", err)
+	}
+	var state NotificationState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("state file: %v", err)
+	}
+	if len(state.Sent["ops"]) != 3 {
+		t.Errorf("state = %v, want the 3 alerts of today", state.Sent)
+	}
+	for key, sentOn := range state.Sent["ops"] {
+		if sentOn != "2026-02-02" {
+			t.Errorf("state records %s on %s, want 2026-02-02", key, sentOn)
+		}
+	}
+}
+
+func TestNotifyFailedWebhookRetried(t *testing.T) {
+	failing, working := newWebhookRecorder(t), newWebhookRecorder(t)
+	failing.status = http.StatusInternalServerError
+	n := newTestNotifier(t, time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC),
+		mustNewWebhook(t, "failing", failing.URL, "json", "", "critical"),
+		mustNewWebhook(t, "working", working.URL, "json", "", "critical"))
+	certs := testAlertCerts()
+
+	result, err := n.Notify(context.Background(), certs)
+	if err == nil || !strings.Contains(err.Error(), "failing") {
+		t.Errorf("err = %v, want the failing webhook reported", err)
+	}
+	if result == nil || result.Sent["working"] != 2 || result.Sent["failing"] != 0 {
+		t.Fatalf("result = %v, want the working webhook notified", result)
+	}
+
+	// Only the alerts of the failed webhook are sent again
+	failing.status = http.StatusOK
+	result, err = n.Notify(context.Background(), certs)
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if result.Sent["failing"] != 2 || result.Sent["working"] != 0 || result.Deduplicated != 2 {
+		t.Errorf("retry: %+v, want the failed alerts sent again", result)
+	}
+}
+
+func TestNotifyTemplateFailure(t *testing.T) {
+	working, broken := newWebhookRecorder(t), newWebhookRecorder(t)
+	n := newTestNotifier(t, time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC),
+		mustNewWebhook(t, "working", working.URL, "json", "", "critical"),
+		// Parses, but fails to execute on an alert
+		mustNewWebhook(t, "broken", broken.URL, "json", "{{.Missing}}", "critical"))
+	certs := testAlertCerts()
+
+	result, err := n.Notify(context.Background(), certs)
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("err = %v, want the broken webhook reported", err)
+	}
+	if result == nil || result.Sent["working"] != 2 || result.Sent["broken"] != 0 || len(broken.received()) != 0 {
+		t.Fatalf("result = %v, want only the working webhook notified", result)
+	}
+
+	// The alerts sent before the failure are recorded
+	result, err = n.Notify(context.Background(), certs)
+	if err == nil {
+		t.Error("second run: the broken webhook succeeded")
+	}
+	if result == nil || result.Sent["working"] != 0 || result.Deduplicated != 2 || len(working.received()) != 1 {
+		t.Errorf("second run: %+v, want the alerts of the working webhook suppressed", result)
+	}
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PersistentVolumeInfo describes a PersistentVolume
+type PersistentVolumeInfo struct {
+	Name          string
+	Phase         string
+	ReclaimPolicy corev1.PersistentVolumeReclaimPolicy
+	Capacity      string
+	StorageClass  string
+	AccessModes   []corev1.PersistentVolumeAccessMode
+	// CSIDriver and VolumeHandle are empty for in-tree volumes
+	CSIDriver    string
+	VolumeHandle string
+	// ClaimNamespace and ClaimName are empty for unbound volumes
+	ClaimNamespace string
+	ClaimName      string
+	// NodeAffinity lists the node selector terms the volume is restricted to
+	NodeAffinity []string
+}
+
+// ListPersistentVolumes returns all PVs in the cluster
+func (vc *VolumeController) ListPersistentVolumes() ([]PersistentVolumeInfo, error) {
+	pvs, err := vc.clientset.CoreV1().PersistentVolumes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PVs: %v", err)
+	}
+
+	var volumes []PersistentVolumeInfo
+	for _, pv := range pvs.Items {
+		info := PersistentVolumeInfo{
+			Name:          pv.Name,
+			Phase:         string(pv.Status.Phase),
+			ReclaimPolicy: pv.Spec.PersistentVolumeReclaimPolicy,
+			Capacity:      pv.Spec.Capacity.Storage().String(),
+			StorageClass:  pv.Spec.StorageClassName,
+			AccessModes:   pv.Spec.AccessModes,
+			NodeAffinity:  formatNodeAffinity(pv.Spec.NodeAffinity),
+		}
+		if csi := pv.Spec.CSI; csi != nil {
+			info.CSIDriver = csi.Driver
+			info.VolumeHandle = csi.VolumeHandle
+		}
+		if ref := pv.Spec.ClaimRef; ref != nil {
+			info.ClaimNamespace = ref.Namespace
+			info.ClaimName = ref.Name
+		}
+		volumes = append(volumes, info)
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].Name < volumes[j].Name
+	})
+	return volumes, nil
+}
+
+// SetReclaimPolicy changes the persistentVolumeReclaimPolicy of a PV, e.g.
+// to Retain before a risky delete so the data outlives its claim
+func (vc *VolumeController) SetReclaimPolicy(name string, policy corev1.PersistentVolumeReclaimPolicy) error {
+	switch policy {
+	case corev1.PersistentVolumeReclaimRetain, corev1.PersistentVolumeReclaimDelete:
+	default:
+		return fmt.Errorf("unsupported reclaim policy %q", policy)
+	}
+
+	patch := fmt.Sprintf(`{"spec":{"persistentVolumeReclaimPolicy":%q}}`, policy)
+	_, err := vc.clientset.CoreV1().PersistentVolumes().Patch(context.TODO(), name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to patch PV %s: %v", name, err)
+	}
+	return nil
+}
+
+func formatNodeAffinity(affinity *corev1.VolumeNodeAffinity) []string {
+	if affinity == nil || affinity.Required == nil {
+		return nil
+	}
+
+	var terms []string
+	for _, term := range affinity.Required.NodeSelectorTerms {
+		var expressions []string
+		for _, expr := range term.MatchExpressions {
+			switch expr.Operator {
+			case corev1.NodeSelectorOpIn, corev1.NodeSelectorOpNotIn:
+				expressions = append(expressions, fmt.Sprintf("%s %s (%s)", expr.Key, strings.ToLower(string(expr.Operator)), strings.Join(expr.Values, ", ")))
+			default:
+				expressions = append(expressions, fmt.Sprintf("%s %s", expr.Key, expr.Operator))
+			}
+		}
+		terms = append(terms, strings.Join(expressions, " and "))
+	}
+	return terms
+}
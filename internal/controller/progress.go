@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ProgressEventType is the kind of a ProgressEvent
+type ProgressEventType int
+
+const (
+	// ProgressStep starts a new step of an operation
+	ProgressStep ProgressEventType = iota
+	// ProgressSuccess completes the current step
+	ProgressSuccess
+	// ProgressDetail adds a detail line to the current step
+	ProgressDetail
+)
+
+// ProgressEvent reports the progress of a long-running operation
+type ProgressEvent struct {
+	Type    ProgressEventType
+	Message string
+	Time    time.Time
+}
+
+// ProgressFunc receives the progress events of an operation. A nil
+// ProgressFunc discards them
+type ProgressFunc func(ProgressEvent)
+
+func (p ProgressFunc) emit(eventType ProgressEventType, format string, args ...interface{}) {
+	if p == nil {
+		return
+	}
+	p(ProgressEvent{
+		Type:    eventType,
+		Message: fmt.Sprintf(format, args...),
+		Time:    time.Now(),
+	})
+}
+
+func (p ProgressFunc) step(format string, args ...interface{}) {
+	p.emit(ProgressStep, format, args...)
+}
+
+func (p ProgressFunc) success(format string, args ...interface{}) {
+	p.emit(ProgressSuccess, format, args...)
+}
+
+func (p ProgressFunc) detail(format string, args ...interface{}) {
+	p.emit(ProgressDetail, format, args...)
+}
+
+// sleepContext waits for d, returning early with the context error if ctx
+// is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,242 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServiceAccountTokenInfo describes a kubernetes.io/service-account-token
+// secret and the claims of its token
+type ServiceAccountTokenInfo struct {
+	Namespace      string
+	Name           string
+	UID            string
+	ServiceAccount string
+	Created        time.Time
+	Age            string
+	// Claims of the JWT. Expires is zero for tokens without exp, which is
+	// the case of every legacy token
+	Issuer   string
+	Subject  string
+	IssuedAt time.Time
+	Expires  time.Time
+	// TokenError is set when the token cannot be decoded
+	TokenError string
+	// ServiceAccountMissing is set when the ServiceAccount was deleted or
+	// recreated since the token was issued
+	ServiceAccountMissing bool
+	// UsedBy lists the pods mounting the secret or reading it in their
+	// environment
+	UsedBy []string
+}
+
+// NonExpiring reports whether the token has no expiry
+func (t *ServiceAccountTokenInfo) NonExpiring() bool {
+	return t.TokenError == "" && t.Expires.IsZero()
+}
+
+// Problems lists the reasons the token is a risk
+func (t *ServiceAccountTokenInfo) Problems() []string {
+	var problems []string
+	if t.TokenError != "" {
+		problems = append(problems, t.TokenError)
+	}
+	if t.NonExpiring() {
+		problems = append(problems, "never expires")
+	}
+	if t.ServiceAccountMissing {
+		problems = append(problems, fmt.Sprintf("ServiceAccount %s no longer exists", t.ServiceAccount))
+	}
+	if len(t.UsedBy) == 0 {
+		problems = append(problems, "not used by any pod")
+	}
+	return problems
+}
+
+type tokenClaims struct {
+	Issuer   string `json:"iss"`
+	Subject  string `json:"sub"`
+	IssuedAt int64  `json:"iat"`
+	Expires  int64  `json:"exp"`
+}
+
+// AuditServiceAccountTokens lists the service account token secrets of
+// every namespace with their claims, whether their ServiceAccount still
+// exists and the pods using them, oldest first
+func (c *CertController) AuditServiceAccountTokens() ([]ServiceAccountTokenInfo, error) {
+	ctx := context.TODO()
+	secrets, err := c.clientset.CoreV1().Secrets("").List(ctx, metav1.ListOptions{
+		FieldSelector: "type=" + string(corev1.SecretTypeServiceAccountToken),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %v", err)
+	}
+	accounts, err := c.clientset.CoreV1().ServiceAccounts("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service accounts: %v", err)
+	}
+	pods, err := c.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+
+	accountUIDs := make(map[string]string)
+	for _, account := range accounts.Items {
+		accountUIDs[account.Namespace+"/"+account.Name] = string(account.UID)
+	}
+	usedBy := make(map[string][]string)
+	for _, pod := range pods.Items {
+		for _, secret := range podSecretNames(&pod) {
+			key := pod.Namespace + "/" + secret
+			usedBy[key] = append(usedBy[key], pod.Name)
+		}
+	}
+
+	var tokens []ServiceAccountTokenInfo
+	for _, secret := range secrets.Items {
+		// The field selector may be ignored by some API servers
+		if secret.Type != corev1.SecretTypeServiceAccountToken {
+			continue
+		}
+		info := ServiceAccountTokenInfo{
+			Namespace:      secret.Namespace,
+			Name:           secret.Name,
+			UID:            string(secret.UID),
+			ServiceAccount: secret.Annotations[corev1.ServiceAccountNameKey],
+			Created:        secret.CreationTimestamp.Time,
+			Age:            formatAge(time.Since(secret.CreationTimestamp.Time)),
+			UsedBy:         usedBy[secret.Namespace+"/"+secret.Name],
+		}
+		uid, ok := accountUIDs[secret.Namespace+"/"+info.ServiceAccount]
+		wantUID := secret.Annotations[corev1.ServiceAccountUIDKey]
+		info.ServiceAccountMissing = !ok || (wantUID != "" && wantUID != uid)
+
+		claims, err := decodeTokenClaims(secret.Data[corev1.ServiceAccountTokenKey])
+		if err != nil {
+			info.TokenError = err.Error()
+		} else {
+			info.Issuer, info.Subject = claims.Issuer, claims.Subject
+			if claims.IssuedAt != 0 {
+				info.IssuedAt = time.Unix(claims.IssuedAt, 0)
+			}
+			if claims.Expires != 0 {
+				info.Expires = time.Unix(claims.Expires, 0)
+			}
+		}
+		tokens = append(tokens, info)
+	}
+
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].Created.Before(tokens[j].Created)
+	})
+	return tokens, nil
+}
+
+// decodeTokenClaims reads the claims of a JWT without verifying its
+// signature
+func decodeTokenClaims(token []byte) (*tokenClaims, error) {
+	if len(token) == 0 {
+		return nil, fmt.Errorf("no token issued yet")
+	}
+	parts := strings.Split(string(token), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid token payload: %v", err)
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %v", err)
+	}
+	return &claims, nil
+}
+
+// podSecretNames returns the secrets a pod mounts, projects or reads in
+// its environment
+func podSecretNames(pod *corev1.Pod) []string {
+	var names []string
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Secret != nil {
+			names = append(names, volume.Secret.SecretName)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil {
+					names = append(names, source.Secret.Name)
+				}
+			}
+		}
+	}
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, container := range containers {
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+				names = append(names, env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef != nil {
+				names = append(names, envFrom.SecretRef.Name)
+			}
+		}
+	}
+
+	// A pod reading a secret several times uses it once
+	seen := make(map[string]bool)
+	unique := names[:0]
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// DeleteServiceAccountToken deletes a token secret found by the audit. It
+// refuses when the secret was replaced since the audit or is used by a pod
+func (c *CertController) DeleteServiceAccountToken(token *ServiceAccountTokenInfo) error {
+	ctx := context.TODO()
+	secrets := c.clientset.CoreV1().Secrets(token.Namespace)
+	secret, err := secrets.Get(ctx, token.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get secret: %v", err)
+	}
+	if secret.Type != corev1.SecretTypeServiceAccountToken {
+		return fmt.Errorf("secret %s/%s is not a service account token", token.Namespace, token.Name)
+	}
+	if string(secret.UID) != token.UID {
+		return fmt.Errorf("secret %s/%s was recreated since the audit, refresh it first", token.Namespace, token.Name)
+	}
+
+	pods, err := c.clientset.CoreV1().Pods(token.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %v", err)
+	}
+	for _, pod := range pods.Items {
+		for _, name := range podSecretNames(&pod) {
+			if name == token.Name {
+				return fmt.Errorf("secret %s/%s is used by pod %s", token.Namespace, token.Name, pod.Name)
+			}
+		}
+	}
+
+	uid := secret.UID
+	err = secrets.Delete(ctx, token.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &uid},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete secret %s/%s: %v", token.Namespace, token.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestJWT returns an unsigned JWT carrying payload as its claims
+func newTestJWT(payload string) []byte {
+	encode := base64.RawURLEncoding.EncodeToString
+	return []byte(encode([]byte(`{"alg":"RS256"}`)) + "." + encode([]byte(payload)) + ".signature")
+}
+
+func TestDecodeTokenClaims(t *testing.T) {
+	expired := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	tests := []struct {
+		name    string
+		token   []byte
+		want    *tokenClaims
+		wantErr string
+	}{
+		{
+			name:  "legacy token without expiry",
+			token: newTestJWT(`{"iss":"kubernetes/serviceaccount","sub":"system:serviceaccount:default:builder"}`),
+			want:  &tokenClaims{Issuer: "kubernetes/serviceaccount", Subject: "system:serviceaccount:default:builder"},
+		},
+		{
+			name:  "expired token",
+			token: newTestJWT(`{"iss":"https://kubernetes.default.svc","sub":"system:serviceaccount:default:builder","iat":1704067200,"exp":1735689600}`),
+			want: &tokenClaims{
+				Issuer:   "https://kubernetes.default.svc",
+				Subject:  "system:serviceaccount:default:builder",
+				IssuedAt: 1704067200,
+				Expires:  expired,
+			},
+		},
+		{
+			name:  "padded payload",
+			token: []byte("e30." + base64.URLEncoding.EncodeToString([]byte(`{"sub":"a"}`)) + ".sig"),
+			want:  &tokenClaims{Subject: "a"},
+		},
+		{name: "empty", token: nil, wantErr: "no token issued yet"},
+		{name: "not a JWT", token: []byte("opaque-token"), wantErr: "token is not a JWT"},
+		{name: "too many parts", token: []byte("a.b.c.d"), wantErr: "token is not a JWT"},
+		{name: "invalid base64", token: []byte("e30.!!!.sig"), wantErr: "invalid token payload"},
+		{name: "invalid JSON", token: newTestJWT(`{"exp":`), wantErr: "invalid token claims"},
+		{name: "wrong claim type", token: newTestJWT(`{"exp":"never"}`), wantErr: "invalid token claims"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := decodeTokenClaims(tt.token)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeTokenClaims: %v", err)
+			}
+			if !reflect.DeepEqual(claims, tt.want) {
+				t.Errorf("claims = %+v, want %+v", claims, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceAccountTokenProblems(t *testing.T) {
+	expires := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		token ServiceAccountTokenInfo
+		want  []string
+	}{
+		{"used token with expiry", ServiceAccountTokenInfo{Expires: expires, UsedBy: []string{"app"}}, nil},
+		{"legacy token", ServiceAccountTokenInfo{UsedBy: []string{"app"}}, []string{"never expires"}},
+		{"undecodable token", ServiceAccountTokenInfo{TokenError: "token is not a JWT", UsedBy: []string{"app"}},
+			[]string{"token is not a JWT"}},
+		{"orphaned token", ServiceAccountTokenInfo{ServiceAccount: "builder", ServiceAccountMissing: true, Expires: expires},
+			[]string{"ServiceAccount builder no longer exists", "not used by any pod"}},
+	}
+	for _, tt := range tests {
+		if got := tt.token.Problems(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: problems = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	snapshotGroup = "snapshot.storage.k8s.io"
+	// defaultSnapshotClassAnnotation marks the default VolumeSnapshotClass
+	defaultSnapshotClassAnnotation = "snapshot.storage.kubernetes.io/is-default-class"
+
+	snapshotTimeout    = 10 * time.Minute
+	snapshotPollPeriod = 2 * time.Second
+)
+
+var (
+	volumeSnapshotGVR = schema.GroupVersionResource{
+		Group:    snapshotGroup,
+		Version:  "v1",
+		Resource: "volumesnapshots",
+	}
+	volumeSnapshotClassGVR = schema.GroupVersionResource{
+		Group:    snapshotGroup,
+		Version:  "v1",
+		Resource: "volumesnapshotclasses",
+	}
+)
+
+// SnapshotClassInfo describes a CSI VolumeSnapshotClass
+type SnapshotClassInfo struct {
+	Name           string
+	Driver         string
+	DeletionPolicy string
+	IsDefault      bool
+}
+
+// SnapshotInfo describes a CSI VolumeSnapshot
+type SnapshotInfo struct {
+	Name          string
+	Namespace     string
+	SourcePVC     string
+	SnapshotClass string
+	ReadyToUse    bool
+	RestoreSize   string
+	CreationTime  time.Time
+}
+
+// ListSnapshots returns the VolumeSnapshots taken from a PVC. An empty
+// pvcName returns every snapshot in the namespace. Clusters without the
+// snapshot CRDs have no snapshots
+func (vc *VolumeController) ListSnapshots(namespace, pvcName string) ([]SnapshotInfo, error) {
+	list, err := vc.dynamicClient.Resource(volumeSnapshotGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list volume snapshots: %v", err)
+	}
+
+	var snapshots []SnapshotInfo
+	for _, item := range list.Items {
+		snapshot := snapshotInfoFromUnstructured(&item)
+		if pvcName != "" && snapshot.SourcePVC != pvcName {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreationTime.After(snapshots[j].CreationTime)
+	})
+	return snapshots, nil
+}
+
+func snapshotInfoFromUnstructured(obj *unstructured.Unstructured) SnapshotInfo {
+	source, _, _ := unstructured.NestedString(obj.Object, "spec", "source", "persistentVolumeClaimName")
+	class, _, _ := unstructured.NestedString(obj.Object, "spec", "volumeSnapshotClassName")
+	ready, _, _ := unstructured.NestedBool(obj.Object, "status", "readyToUse")
+	restoreSize, _, _ := unstructured.NestedString(obj.Object, "status", "restoreSize")
+
+	return SnapshotInfo{
+		Name:          obj.GetName(),
+		Namespace:     obj.GetNamespace(),
+		SourcePVC:     source,
+		SnapshotClass: class,
+		ReadyToUse:    ready,
+		RestoreSize:   restoreSize,
+		CreationTime:  obj.GetCreationTimestamp().Time,
+	}
+}
+
+// ListSnapshotClasses returns the VolumeSnapshotClasses of the cluster, the
+// default class first
+func (vc *VolumeController) ListSnapshotClasses() ([]SnapshotClassInfo, error) {
+	list, err := vc.dynamicClient.Resource(volumeSnapshotClassGVR).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("volume snapshots are not supported by this cluster")
+		}
+		return nil, fmt.Errorf("failed to list volume snapshot classes: %v", err)
+	}
+
+	var classes []SnapshotClassInfo
+	for _, item := range list.Items {
+		driver, _, _ := unstructured.NestedString(item.Object, "driver")
+		policy, _, _ := unstructured.NestedString(item.Object, "deletionPolicy")
+		classes = append(classes, SnapshotClassInfo{
+			Name:           item.GetName(),
+			Driver:         driver,
+			DeletionPolicy: policy,
+			IsDefault:      item.GetAnnotations()[defaultSnapshotClassAnnotation] == "true",
+		})
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if classes[i].IsDefault != classes[j].IsDefault {
+			return classes[i].IsDefault
+		}
+		return classes[i].Name < classes[j].Name
+	})
+	return classes, nil
+}
+
+// CreateSnapshot takes a VolumeSnapshot of a PVC with the given class and
+// waits until it is ready to use
+func (vc *VolumeController) CreateSnapshot(ctx context.Context, namespace, pvcName, className string, progress ProgressFunc) (*SnapshotInfo, error) {
+	progress.step("Checking PVC %s", pvcName)
+	if _, err := vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, pvcName, metav1.GetOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to get PVC: %v", err)
+	}
+
+	name := fmt.Sprintf("%s-%s", pvcName, time.Now().Format("20060102-150405"))
+	snapshot := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": snapshotGroup + "/v1",
+		"kind":       "VolumeSnapshot",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+		},
+		"spec": map[string]interface{}{
+			"volumeSnapshotClassName": className,
+			"source": map[string]interface{}{
+				"persistentVolumeClaimName": pvcName,
+			},
+		},
+	}}
+
+	progress.step("Creating snapshot %s (class %s)", name, className)
+	_, err := vc.dynamicClient.Resource(volumeSnapshotGVR).Namespace(namespace).Create(ctx, snapshot, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create volume snapshot: %v", err)
+	}
+
+	progress.step("Waiting for snapshot %s to be ready...", name)
+	info, err := vc.waitForSnapshotReady(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	progress.success("Snapshot %s is ready (%s)", name, info.RestoreSize)
+	return info, nil
+}
+
+func (vc *VolumeController) waitForSnapshotReady(ctx context.Context, namespace, name string) (*SnapshotInfo, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, snapshotTimeout)
+	defer cancel()
+
+	for {
+		obj, err := vc.dynamicClient.Resource(volumeSnapshotGVR).Namespace(namespace).Get(timeoutCtx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get volume snapshot: %v", err)
+		}
+
+		info := snapshotInfoFromUnstructured(obj)
+		if info.ReadyToUse {
+			return &info, nil
+		}
+		if message, ok, _ := unstructured.NestedString(obj.Object, "status", "error", "message"); ok && message != "" {
+			return nil, fmt.Errorf("snapshot %s failed: %s", name, message)
+		}
+
+		if err := sleepContext(timeoutCtx, snapshotPollPeriod); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("timeout waiting for snapshot %s to be ready", name)
+		}
+	}
+}
+
+// RestoreSnapshot creates a new PVC named newPVCName from a ready
+// VolumeSnapshot. The new PVC inherits the StorageClass and access modes
+// of the snapshot's source PVC when it still exists
+func (vc *VolumeController) RestoreSnapshot(ctx context.Context, namespace, snapshotName, newPVCName string, progress ProgressFunc) error {
+	progress.step("Checking snapshot %s", snapshotName)
+	obj, err := vc.dynamicClient.Resource(volumeSnapshotGVR).Namespace(namespace).Get(ctx, snapshotName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get volume snapshot: %v", err)
+	}
+	snapshot := snapshotInfoFromUnstructured(obj)
+	if !snapshot.ReadyToUse {
+		return fmt.Errorf("snapshot %s is not ready to use", snapshotName)
+	}
+
+	apiGroup := snapshotGroup
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      newPVCName,
+			Namespace: namespace,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			DataSource: &corev1.TypedLocalObjectReference{
+				APIGroup: &apiGroup,
+				Kind:     "VolumeSnapshot",
+				Name:     snapshotName,
+			},
+		},
+	}
+
+	size := snapshot.RestoreSize
+	source, err := vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, snapshot.SourcePVC, metav1.GetOptions{})
+	if err == nil {
+		pvc.Spec.StorageClassName = source.Spec.StorageClassName
+		pvc.Spec.AccessModes = source.Spec.AccessModes
+		pvc.Spec.VolumeMode = source.Spec.VolumeMode
+		if size == "" {
+			size = source.Spec.Resources.Requests.Storage().String()
+		}
+	} else if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get source PVC: %v", err)
+	} else {
+		progress.detail("Source PVC %s is gone, using the default StorageClass", snapshot.SourcePVC)
+	}
+	if size == "" {
+		return fmt.Errorf("cannot determine the size of snapshot %s", snapshotName)
+	}
+
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot size %q: %v", size, err)
+	}
+	pvc.Spec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: quantity}
+
+	progress.step("Creating PVC %s (%s) from snapshot %s", newPVCName, quantity.String(), snapshotName)
+	_, err = vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, pvc, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create PVC: %v", err)
+	}
+
+	progress.success("PVC %s created, it is provisioned from the snapshot once bound", newPVCName)
+	return nil
+}
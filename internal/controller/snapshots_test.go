@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newSnapshotDynamicClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		volumeSnapshotGVR:      "VolumeSnapshotList",
+		volumeSnapshotClassGVR: "VolumeSnapshotClassList",
+	}, objects...)
+}
+
+func newTestSnapshot(name, pvcName string, ready bool, restoreSize string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": snapshotGroup + "/v1",
+		"kind":       "VolumeSnapshot",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "default",
+		},
+		"spec": map[string]interface{}{
+			"volumeSnapshotClassName": "csi-snapclass",
+			"source": map[string]interface{}{
+				"persistentVolumeClaimName": pvcName,
+			},
+		},
+		"status": map[string]interface{}{
+			"readyToUse":  ready,
+			"restoreSize": restoreSize,
+		},
+	}}
+}
+
+func newTestPVC(name, storageClass string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &storageClass,
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("5Gi")},
+			},
+		},
+	}
+}
+
+func TestListSnapshotsWithoutCRD(t *testing.T) {
+	dynamicClient := newSnapshotDynamicClient()
+	dynamicClient.PrependReactor("list", "volumesnapshots", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(volumeSnapshotGVR.GroupResource(), "")
+	})
+	vc := NewVolumeController(fake.NewSimpleClientset(), dynamicClient, nil)
+
+	snapshots, err := vc.ListSnapshots("default", "data")
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	if snapshots != nil {
+		t.Errorf("snapshots = %v, want nil", snapshots)
+	}
+}
+
+func TestListSnapshotsFiltersByPVC(t *testing.T) {
+	dynamicClient := newSnapshotDynamicClient(
+		newTestSnapshot("data-1", "data", true, "1Gi"),
+		newTestSnapshot("logs-1", "logs", true, "1Gi"),
+	)
+	vc := NewVolumeController(fake.NewSimpleClientset(), dynamicClient, nil)
+
+	snapshots, err := vc.ListSnapshots("default", "data")
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Name != "data-1" || !snapshots[0].ReadyToUse {
+		t.Errorf("snapshots = %+v, want the ready snapshot data-1", snapshots)
+	}
+}
+
+func TestListSnapshotClassesDefaultFirst(t *testing.T) {
+	class := func(name string, isDefault bool) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion":     snapshotGroup + "/v1",
+			"kind":           "VolumeSnapshotClass",
+			"metadata":       map[string]interface{}{"name": name},
+			"driver":         "csi.example.com",
+			"deletionPolicy": "Delete",
+		}}
+		if isDefault {
+			obj.SetAnnotations(map[string]string{defaultSnapshotClassAnnotation: "true"})
+		}
+		return obj
+	}
+	vc := NewVolumeController(fake.NewSimpleClientset(), newSnapshotDynamicClient(class("a", false), class("b", true)), nil)
+
+	classes, err := vc.ListSnapshotClasses()
+	if err != nil {
+		t.Fatalf("ListSnapshotClasses: %v", err)
+	}
+	if len(classes) != 2 || classes[0].Name != "b" || !classes[0].IsDefault || classes[0].Driver != "csi.example.com" {
+		t.Errorf("classes = %+v, want the default class b first", classes)
+	}
+}
+
+func TestCreateSnapshotWaitsForReadyToUse(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newTestPVC("data", "fast"))
+	dynamicClient := newSnapshotDynamicClient()
+
+	// The snapshot controller marks the snapshot ready after the first poll
+	gets := 0
+	dynamicClient.PrependReactor("get", "volumesnapshots", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		gets++
+		if gets < 2 {
+			return false, nil, nil
+		}
+		name := action.(k8stesting.GetAction).GetName()
+		obj, err := dynamicClient.Tracker().Get(volumeSnapshotGVR, "default", name)
+		if err != nil {
+			return true, nil, err
+		}
+		snapshot := obj.(*unstructured.Unstructured).DeepCopy()
+		unstructured.SetNestedField(snapshot.Object, true, "status", "readyToUse")
+		unstructured.SetNestedField(snapshot.Object, "5Gi", "status", "restoreSize")
+		return true, snapshot, nil
+	})
+	vc := NewVolumeController(clientset, dynamicClient, nil)
+
+	info, err := vc.CreateSnapshot(context.Background(), "default", "data", "csi-snapclass", nil)
+	if err != nil {
+		t.Fatalf("CreateSnapshot: %v", err)
+	}
+	if gets < 2 {
+		t.Errorf("snapshot polled %d times, want it polled until ready", gets)
+	}
+	if !info.ReadyToUse || info.RestoreSize != "5Gi" || info.SourcePVC != "data" || info.SnapshotClass != "csi-snapclass" {
+		t.Errorf("snapshot = %+v, want a ready snapshot of data", info)
+	}
+}
+
+func TestCreateSnapshotCancelled(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newTestPVC("data", "fast"))
+	vc := NewVolumeController(clientset, newSnapshotDynamicClient(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	progress := func(event ProgressEvent) {
+		// Cancel once the snapshot is created and the wait starts
+		if event.Type == ProgressStep && strings.HasPrefix(event.Message, "Waiting") {
+			cancel()
+		}
+	}
+	if _, err := vc.CreateSnapshot(ctx, "default", "data", "csi-snapclass", progress); err != context.Canceled {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestRestoreSnapshotSetsDataSource(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newTestPVC("data", "fast"))
+	vc := NewVolumeController(clientset, newSnapshotDynamicClient(newTestSnapshot("data-1", "data", true, "5Gi")), nil)
+
+	if err := vc.RestoreSnapshot(context.Background(), "default", "data-1", "data-restore", nil); err != nil {
+		t.Fatalf("RestoreSnapshot: %v", err)
+	}
+	pvc, err := clientset.CoreV1().PersistentVolumeClaims("default").Get(context.Background(), "data-restore", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("restored PVC: %v", err)
+	}
+
+	source := pvc.Spec.DataSource
+	if source == nil || source.APIGroup == nil || *source.APIGroup != snapshotGroup || source.Kind != "VolumeSnapshot" || source.Name != "data-1" {
+		t.Errorf("dataSource = %+v, want VolumeSnapshot data-1", source)
+	}
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName != "fast" {
+		t.Errorf("storageClassName = %v, want the class of the source PVC", pvc.Spec.StorageClassName)
+	}
+	if len(pvc.Spec.AccessModes) != 1 || pvc.Spec.AccessModes[0] != corev1.ReadWriteMany {
+		t.Errorf("accessModes = %v, want those of the source PVC", pvc.Spec.AccessModes)
+	}
+	if size := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; size.String() != "5Gi" {
+		t.Errorf("size = %s, want the restore size 5Gi", size.String())
+	}
+}
+
+func TestRestoreSnapshotNotReady(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	vc := NewVolumeController(clientset, newSnapshotDynamicClient(newTestSnapshot("data-1", "data", false, "")), nil)
+
+	if err := vc.RestoreSnapshot(context.Background(), "default", "data-1", "data-restore", nil); err == nil {
+		t.Fatal("RestoreSnapshot of a snapshot not ready to use succeeded")
+	}
+	if _, err := clientset.CoreV1().PersistentVolumeClaims("default").Get(context.Background(), "data-restore", metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("restored PVC created for a snapshot not ready to use")
+	}
+}
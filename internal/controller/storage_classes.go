@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultStorageClassAnnotation marks the default StorageClass
+const defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+// StorageClassInfo describes a StorageClass
+type StorageClassInfo struct {
+	Name                 string
+	Provisioner          string
+	ReclaimPolicy        string
+	VolumeBindingMode    string
+	AllowVolumeExpansion bool
+	IsDefault            bool
+}
+
+// ListStorageClasses returns the StorageClasses of the cluster, the default
+// class first
+func (vc *VolumeController) ListStorageClasses() ([]StorageClassInfo, error) {
+	list, err := vc.clientset.StorageV1().StorageClasses().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage classes: %v", err)
+	}
+
+	var classes []StorageClassInfo
+	for _, sc := range list.Items {
+		info := StorageClassInfo{
+			Name:                 sc.Name,
+			Provisioner:          sc.Provisioner,
+			AllowVolumeExpansion: sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion,
+			IsDefault:            sc.Annotations[defaultStorageClassAnnotation] == "true",
+		}
+		if sc.ReclaimPolicy != nil {
+			info.ReclaimPolicy = string(*sc.ReclaimPolicy)
+		}
+		if sc.VolumeBindingMode != nil {
+			info.VolumeBindingMode = string(*sc.VolumeBindingMode)
+		}
+		classes = append(classes, info)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if classes[i].IsDefault != classes[j].IsDefault {
+			return classes[i].IsDefault
+		}
+		return classes[i].Name < classes[j].Name
+	})
+	return classes, nil
+}
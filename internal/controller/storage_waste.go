@@ -0,0 +1,290 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WasteCategory classifies the entries of a StorageWasteReport
+type WasteCategory string
+
+const (
+	WasteUnmountedPVC WasteCategory = "Unmounted PVCs"
+	WasteReleasedPV   WasteCategory = "Released PVs"
+	WasteFailedPV     WasteCategory = "Failed PVs"
+	WasteOrphanedPV   WasteCategory = "Retained PVs without claim"
+)
+
+// WasteCategories lists the categories in report order
+var WasteCategories = []WasteCategory{WasteUnmountedPVC, WasteReleasedPV, WasteFailedPV, WasteOrphanedPV}
+
+// WasteItem is a PVC or PV holding storage nothing uses
+type WasteItem struct {
+	Category WasteCategory
+	// Namespace is empty for PVs
+	Namespace     string
+	Name          string
+	StorageClass  string
+	Capacity      string
+	CapacityBytes int64
+	Reason        string
+}
+
+// IsPVC reports whether the item is a PVC rather than a PV
+func (w WasteItem) IsPVC() bool {
+	return w.Category == WasteUnmountedPVC
+}
+
+// StorageWasteReport lists unused storage grouped by category
+type StorageWasteReport struct {
+	Items []WasteItem
+	// TotalBytes is the wasted capacity per category
+	TotalBytes map[WasteCategory]int64
+}
+
+// FindStorageWaste reports PVCs not mounted by any pod or workload
+// template, PVs in Released or Failed phase and Retain PVs whose claim is
+// gone
+func (vc *VolumeController) FindStorageWaste() (*StorageWasteReport, error) {
+	ctx := context.TODO()
+
+	pvcs, err := vc.clientset.CoreV1().PersistentVolumeClaims("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PVCs: %v", err)
+	}
+	pvs, err := vc.clientset.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PVs: %v", err)
+	}
+	mounted, err := vc.mountedClaims(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StorageWasteReport{TotalBytes: make(map[WasteCategory]int64)}
+	add := func(item WasteItem) {
+		report.Items = append(report.Items, item)
+		report.TotalBytes[item.Category] += item.CapacityBytes
+	}
+
+	claims := make(map[string]corev1.PersistentVolumeClaim)
+	for _, pvc := range pvcs.Items {
+		key := pvc.Namespace + "/" + pvc.Name
+		claims[key] = pvc
+		if mounted(pvc.Namespace, pvc.Name) {
+			continue
+		}
+
+		capacity := pvc.Spec.Resources.Requests.Storage()
+		if status, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+			capacity = &status
+		}
+		add(WasteItem{
+			Category:      WasteUnmountedPVC,
+			Namespace:     pvc.Namespace,
+			Name:          pvc.Name,
+			StorageClass:  getStorageClassName(pvc.Spec.StorageClassName),
+			Capacity:      capacity.String(),
+			CapacityBytes: capacity.Value(),
+			Reason:        "not mounted by any pod or workload template",
+		})
+	}
+
+	for _, pv := range pvs.Items {
+		item := WasteItem{
+			Name:          pv.Name,
+			StorageClass:  pv.Spec.StorageClassName,
+			Capacity:      pv.Spec.Capacity.Storage().String(),
+			CapacityBytes: pv.Spec.Capacity.Storage().Value(),
+		}
+
+		claimGone := false
+		if ref := pv.Spec.ClaimRef; ref != nil {
+			claim, ok := claims[ref.Namespace+"/"+ref.Name]
+			claimGone = !ok || (ref.UID != "" && claim.UID != ref.UID)
+		}
+
+		switch {
+		case pv.Status.Phase == corev1.VolumeFailed:
+			item.Category = WasteFailedPV
+			item.Reason = pv.Status.Message
+			if item.Reason == "" {
+				item.Reason = "volume reclamation failed"
+			}
+		case pv.Spec.PersistentVolumeReclaimPolicy == corev1.PersistentVolumeReclaimRetain && claimGone:
+			item.Category = WasteOrphanedPV
+			item.Reason = fmt.Sprintf("claim %s/%s no longer exists", pv.Spec.ClaimRef.Namespace, pv.Spec.ClaimRef.Name)
+		case pv.Status.Phase == corev1.VolumeReleased:
+			item.Category = WasteReleasedPV
+			item.Reason = fmt.Sprintf("released, reclaim policy %s", pv.Spec.PersistentVolumeReclaimPolicy)
+		default:
+			continue
+		}
+		add(item)
+	}
+
+	order := make(map[WasteCategory]int)
+	for i, category := range WasteCategories {
+		order[category] = i
+	}
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		a, b := report.Items[i], report.Items[j]
+		if a.Category != b.Category {
+			return order[a.Category] < order[b.Category]
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return report, nil
+}
+
+// mountedClaims returns a function reporting whether a claim is referenced
+// by any pod or workload pod template in the cluster
+func (vc *VolumeController) mountedClaims(ctx context.Context) (func(namespace, name string) bool, error) {
+	used := make(map[string]bool)
+	addSpec := func(namespace string, spec corev1.PodSpec) {
+		for _, volume := range spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				used[namespace+"/"+volume.PersistentVolumeClaim.ClaimName] = true
+			}
+		}
+	}
+	pods, err := vc.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+	for _, pod := range pods.Items {
+		addSpec(pod.Namespace, pod.Spec)
+	}
+
+	deployments, err := vc.clientset.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %v", err)
+	}
+	for _, d := range deployments.Items {
+		addSpec(d.Namespace, d.Spec.Template.Spec)
+	}
+
+	statefulSets, err := vc.clientset.AppsV1().StatefulSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %v", err)
+	}
+	for _, sts := range statefulSets.Items {
+		addSpec(sts.Namespace, sts.Spec.Template.Spec)
+	}
+
+	replicaSets, err := vc.clientset.AppsV1().ReplicaSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replicasets: %v", err)
+	}
+	for _, rs := range replicaSets.Items {
+		addSpec(rs.Namespace, rs.Spec.Template.Spec)
+	}
+
+	daemonSets, err := vc.clientset.AppsV1().DaemonSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %v", err)
+	}
+	for _, ds := range daemonSets.Items {
+		addSpec(ds.Namespace, ds.Spec.Template.Spec)
+	}
+
+	jobs, err := vc.clientset.BatchV1().Jobs("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %v", err)
+	}
+	for _, job := range jobs.Items {
+		addSpec(job.Namespace, job.Spec.Template.Spec)
+	}
+
+	cronJobs, err := vc.clientset.BatchV1().CronJobs("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cronjobs: %v", err)
+	}
+	for _, cj := range cronJobs.Items {
+		addSpec(cj.Namespace, cj.Spec.JobTemplate.Spec.Template.Spec)
+	}
+
+	return func(namespace, name string) bool {
+		if used[namespace+"/"+name] {
+			return true
+		}
+		// Scaled-down StatefulSets keep the claims of their templates
+		for i := range statefulSets.Items {
+			sts := &statefulSets.Items[i]
+			if sts.Namespace == namespace && statefulSetOwnsClaim(sts, name) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// CleanupStorageWaste deletes the given items. PVCs go through the same
+// plan and verification as DeleteVolume; PVs are only deleted if they are
+// still unbound. Every item is attempted, and the failures are returned
+// together
+func (vc *VolumeController) CleanupStorageWaste(ctx context.Context, items []WasteItem, progress ProgressFunc) error {
+	var errs []string
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var err error
+		if item.IsPVC() {
+			err = vc.DeleteVolume(ctx, item.Namespace, item.Name, DeleteVolumeOptions{}, progress)
+		} else {
+			err = vc.deleteUnboundPV(ctx, item.Name, progress)
+		}
+		if err != nil {
+			progress.detail("Failed: %v", err)
+			errs = append(errs, fmt.Sprintf("%s: %v", item.Name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d item(s) failed:\n%s", len(errs), len(items), strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+func (vc *VolumeController) deleteUnboundPV(ctx context.Context, name string, progress ProgressFunc) error {
+	progress.step("Deleting PV %s...", name)
+
+	pv, err := vc.clientset.CoreV1().PersistentVolumes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get PV: %v", err)
+	}
+	if pv.Status.Phase == corev1.VolumeBound {
+		return fmt.Errorf("PV %s is bound again, skipping", name)
+	}
+
+	err = vc.clientset.CoreV1().PersistentVolumes().Delete(ctx, name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &pv.UID},
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete PV: %v", err)
+	}
+
+	for i := 0; i < 30; i++ {
+		_, err := vc.clientset.CoreV1().PersistentVolumes().Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			progress.success("PV %s deleted (%s freed)", name, pv.Spec.Capacity.Storage().String())
+			return nil
+		}
+		if err := sleepContext(ctx, time.Second); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("timeout waiting for PV %s to be deleted", name)
+}
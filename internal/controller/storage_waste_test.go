@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMountedClaims(t *testing.T) {
+	// A StatefulSet scaled to zero, with no pods left
+	zero := int32(0)
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &zero,
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+				ObjectMeta: metav1.ObjectMeta{Name: "data"},
+			}},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{{
+				Name: "logs",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "logs"},
+				},
+			}},
+		},
+	}
+	vc := NewVolumeController(fake.NewSimpleClientset(sts, pod), nil, nil)
+
+	mounted, err := vc.mountedClaims(context.Background())
+	if err != nil {
+		t.Fatalf("mountedClaims: %v", err)
+	}
+	tests := []struct {
+		namespace, name string
+		want            bool
+	}{
+		{"default", "logs", true},
+		{"other", "logs", false},
+		{"default", "data-web-0", true},
+		{"default", "data-web-12", true},
+		{"other", "data-web-0", false},
+		{"default", "data-web-backup", false},
+		{"default", "data-web-", false},
+		{"default", "cache", false},
+	}
+	for _, tt := range tests {
+		if got := mounted(tt.namespace, tt.name); got != tt.want {
+			t.Errorf("mounted(%q, %q) = %v, want %v", tt.namespace, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Finalizers kubegreen can explain
+const (
+	pvcProtectionFinalizer          = "kubernetes.io/pvc-protection"
+	pvProtectionFinalizer           = "kubernetes.io/pv-protection"
+	externalProvisionerFinalizer    = "external-provisioner.volume.kubernetes.io/finalizer"
+	externalAttacherFinalizerPrefix = "external-attacher/"
+)
+
+// Kinds of the objects a StuckDiagnosis covers
+const (
+	KindPersistentVolumeClaim = "PersistentVolumeClaim"
+	KindPersistentVolume      = "PersistentVolume"
+	KindVolumeAttachment      = "VolumeAttachment"
+)
+
+// FinalizerRef identifies a finalizer on an object
+type FinalizerRef struct {
+	Kind string
+	// Namespace is empty for cluster scoped objects
+	Namespace string
+	Name      string
+	Finalizer string
+}
+
+func (f FinalizerRef) String() string {
+	name := f.Name
+	if f.Namespace != "" {
+		name = f.Namespace + "/" + f.Name
+	}
+	return fmt.Sprintf("%s on %s %s", f.Finalizer, f.Kind, name)
+}
+
+// ReferencingPod is a pod that still mounts a PVC
+type ReferencingPod struct {
+	Name  string
+	Phase string
+	Node  string
+	// Terminating is set when the pod was deleted but is still present
+	Terminating bool
+	Since       time.Time
+}
+
+// VolumeAttachmentInfo describes a VolumeAttachment of a PV
+type VolumeAttachmentInfo struct {
+	Name        string
+	Node        string
+	Attacher    string
+	Attached    bool
+	AttachError string
+	DetachError string
+	Finalizers  []string
+}
+
+// StuckDiagnosis explains what keeps a PVC or PV from being deleted
+type StuckDiagnosis struct {
+	Namespace string
+	PVCName   string
+	PVName    string
+
+	// PVCDeleting and PVDeleting are set once deletion was requested
+	PVCDeleting bool
+	PVCDeleted  time.Time
+	PVDeleting  bool
+	PVDeleted   time.Time
+
+	Pods              []ReferencingPod
+	VolumeAttachments []VolumeAttachmentInfo
+	// Finalizers lists every finalizer still set on the PVC, the PV and
+	// its VolumeAttachments
+	Finalizers []FinalizerRef
+	// Reasons explains in plain words why the objects are stuck
+	Reasons []string
+}
+
+// DiagnosePVC explains why a PVC, and the PV bound to it, is not deleted
+func (vc *VolumeController) DiagnosePVC(namespace, name string) (*StuckDiagnosis, error) {
+	ctx := context.TODO()
+
+	pvc, err := vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PVC: %v", err)
+	}
+
+	d := &StuckDiagnosis{Namespace: namespace, PVCName: name, PVName: pvc.Spec.VolumeName}
+	if err := vc.diagnosePVC(ctx, d, pvc); err != nil {
+		return nil, err
+	}
+	if d.PVName != "" {
+		pv, err := vc.clientset.CoreV1().PersistentVolumes().Get(ctx, d.PVName, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get PV %s: %v", d.PVName, err)
+		}
+		if err == nil {
+			if err := vc.diagnosePV(ctx, d, pv); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(d.Reasons) == 0 {
+		d.Reasons = append(d.Reasons, "nothing is blocking the deletion of the PVC")
+	}
+	return d, nil
+}
+
+// DiagnosePV explains why a PV is not deleted
+func (vc *VolumeController) DiagnosePV(name string) (*StuckDiagnosis, error) {
+	ctx := context.TODO()
+
+	pv, err := vc.clientset.CoreV1().PersistentVolumes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PV: %v", err)
+	}
+
+	d := &StuckDiagnosis{PVName: name}
+	if err := vc.diagnosePV(ctx, d, pv); err != nil {
+		return nil, err
+	}
+	if len(d.Reasons) == 0 {
+		d.Reasons = append(d.Reasons, "nothing is blocking the deletion of the PV")
+	}
+	return d, nil
+}
+
+func (vc *VolumeController) diagnosePVC(ctx context.Context, d *StuckDiagnosis, pvc *corev1.PersistentVolumeClaim) error {
+	if pvc.DeletionTimestamp != nil {
+		d.PVCDeleting = true
+		d.PVCDeleted = pvc.DeletionTimestamp.Time
+	}
+	for _, finalizer := range pvc.Finalizers {
+		d.Finalizers = append(d.Finalizers, FinalizerRef{
+			Kind:      KindPersistentVolumeClaim,
+			Namespace: pvc.Namespace,
+			Name:      pvc.Name,
+			Finalizer: finalizer,
+		})
+	}
+
+	pods, err := vc.findPodsUsingPVC(pvc.Namespace, pvc.Name)
+	if err != nil {
+		return fmt.Errorf("failed to check for pods using PVC: %v", err)
+	}
+	var names []string
+	for _, pod := range pods {
+		ref := ReferencingPod{
+			Name:  pod.Name,
+			Phase: string(pod.Status.Phase),
+			Node:  pod.Spec.NodeName,
+		}
+		if pod.DeletionTimestamp != nil {
+			ref.Terminating = true
+			ref.Since = pod.DeletionTimestamp.Time
+			d.Reasons = append(d.Reasons, fmt.Sprintf("pod %s has been terminating for %s on node %s, check whether the node is still reachable",
+				pod.Name, time.Since(ref.Since).Round(time.Second), orNone(pod.Spec.NodeName)))
+		}
+		d.Pods = append(d.Pods, ref)
+		names = append(names, pod.Name)
+	}
+
+	if !d.PVCDeleting {
+		return nil
+	}
+	for _, finalizer := range pvc.Finalizers {
+		switch {
+		case finalizer == pvcProtectionFinalizer && len(pods) > 0:
+			d.Reasons = append(d.Reasons, fmt.Sprintf("%s is kept until no pod references the PVC: %s still do",
+				finalizer, strings.Join(names, ", ")))
+		case finalizer == pvcProtectionFinalizer:
+			d.Reasons = append(d.Reasons, fmt.Sprintf("no pod references the PVC anymore, but %s is still set; "+
+				"the controller should remove it shortly, if it does not it can be removed", finalizer))
+		default:
+			d.Reasons = append(d.Reasons, fmt.Sprintf("finalizer %s is set on the PVC by another controller", finalizer))
+		}
+	}
+	return nil
+}
+
+func (vc *VolumeController) diagnosePV(ctx context.Context, d *StuckDiagnosis, pv *corev1.PersistentVolume) error {
+	if pv.DeletionTimestamp != nil {
+		d.PVDeleting = true
+		d.PVDeleted = pv.DeletionTimestamp.Time
+	}
+	for _, finalizer := range pv.Finalizers {
+		d.Finalizers = append(d.Finalizers, FinalizerRef{
+			Kind:      KindPersistentVolume,
+			Name:      pv.Name,
+			Finalizer: finalizer,
+		})
+	}
+
+	attachments, err := vc.clientset.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list volume attachments: %v", err)
+	}
+	for _, va := range attachments.Items {
+		if va.Spec.Source.PersistentVolumeName == nil || *va.Spec.Source.PersistentVolumeName != pv.Name {
+			continue
+		}
+		info := VolumeAttachmentInfo{
+			Name:       va.Name,
+			Node:       va.Spec.NodeName,
+			Attacher:   va.Spec.Attacher,
+			Attached:   va.Status.Attached,
+			Finalizers: va.Finalizers,
+		}
+		if va.Status.AttachError != nil {
+			info.AttachError = va.Status.AttachError.Message
+		}
+		if va.Status.DetachError != nil {
+			info.DetachError = va.Status.DetachError.Message
+			d.Reasons = append(d.Reasons, fmt.Sprintf("VolumeAttachment %s failed to detach from node %s: %s",
+				va.Name, va.Spec.NodeName, info.DetachError))
+		} else if info.Attached {
+			d.Reasons = append(d.Reasons, fmt.Sprintf("the volume is still attached to node %s (VolumeAttachment %s)",
+				va.Spec.NodeName, va.Name))
+		}
+		for _, finalizer := range va.Finalizers {
+			d.Finalizers = append(d.Finalizers, FinalizerRef{
+				Kind:      KindVolumeAttachment,
+				Name:      va.Name,
+				Finalizer: finalizer,
+			})
+			if va.DeletionTimestamp != nil && strings.HasPrefix(finalizer, externalAttacherFinalizerPrefix) {
+				d.Reasons = append(d.Reasons, fmt.Sprintf("VolumeAttachment %s is being deleted but %s waits for the CSI driver to detach the volume",
+					va.Name, finalizer))
+			}
+		}
+		d.VolumeAttachments = append(d.VolumeAttachments, info)
+	}
+	sort.Slice(d.VolumeAttachments, func(i, j int) bool {
+		return d.VolumeAttachments[i].Name < d.VolumeAttachments[j].Name
+	})
+
+	if !d.PVDeleting {
+		return nil
+	}
+	for _, finalizer := range pv.Finalizers {
+		switch {
+		case finalizer == pvProtectionFinalizer && pv.Status.Phase == corev1.VolumeBound:
+			d.Reasons = append(d.Reasons, fmt.Sprintf("%s is kept while the PV is bound to its claim", finalizer))
+		case finalizer == pvProtectionFinalizer:
+			d.Reasons = append(d.Reasons, fmt.Sprintf("%s is still set although the PV is %s", finalizer, pv.Status.Phase))
+		case finalizer == externalProvisionerFinalizer:
+			d.Reasons = append(d.Reasons, fmt.Sprintf("%s waits for the CSI provisioner to delete the backing volume; "+
+				"removing it leaves that volume behind in the storage backend", finalizer))
+		default:
+			d.Reasons = append(d.Reasons, fmt.Sprintf("finalizer %s is set on the PV by another controller", finalizer))
+		}
+	}
+	return nil
+}
+
+// Summary returns the reasons of a diagnosis on one line
+func (d *StuckDiagnosis) Summary() string {
+	return strings.Join(d.Reasons, "; ")
+}
+
+// RemoveFinalizer removes a single finalizer from a PVC, PV or
+// VolumeAttachment. The patch fails if the finalizers changed since they
+// were read, so a finalizer is never removed by position by mistake
+func (vc *VolumeController) RemoveFinalizer(ref FinalizerRef) error {
+	ctx := context.TODO()
+
+	var finalizers []string
+	switch ref.Kind {
+	case KindPersistentVolumeClaim:
+		pvc, err := vc.clientset.CoreV1().PersistentVolumeClaims(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get PVC: %v", err)
+		}
+		finalizers = pvc.Finalizers
+	case KindPersistentVolume:
+		pv, err := vc.clientset.CoreV1().PersistentVolumes().Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get PV: %v", err)
+		}
+		finalizers = pv.Finalizers
+	case KindVolumeAttachment:
+		va, err := vc.clientset.StorageV1().VolumeAttachments().Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get volume attachment: %v", err)
+		}
+		finalizers = va.Finalizers
+	default:
+		return fmt.Errorf("unsupported kind %s", ref.Kind)
+	}
+
+	index := -1
+	for i, finalizer := range finalizers {
+		if finalizer == ref.Finalizer {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("finalizer %s is no longer set", ref.Finalizer)
+	}
+
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "test", "path": fmt.Sprintf("/metadata/finalizers/%d", index), "value": ref.Finalizer},
+		{"op": "remove", "path": fmt.Sprintf("/metadata/finalizers/%d", index)},
+	})
+	if err != nil {
+		return err
+	}
+
+	switch ref.Kind {
+	case KindPersistentVolumeClaim:
+		_, err = vc.clientset.CoreV1().PersistentVolumeClaims(ref.Namespace).Patch(ctx, ref.Name, types.JSONPatchType, patch, metav1.PatchOptions{})
+	case KindPersistentVolume:
+		_, err = vc.clientset.CoreV1().PersistentVolumes().Patch(ctx, ref.Name, types.JSONPatchType, patch, metav1.PatchOptions{})
+	case KindVolumeAttachment:
+		_, err = vc.clientset.StorageV1().VolumeAttachments().Patch(ctx, ref.Name, types.JSONPatchType, patch, metav1.PatchOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove %s: %v", ref, err)
+	}
+	return nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
@@ -6,7 +6,11 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
@@ -14,27 +18,10 @@ import (
 const (
 	podTimeout    = 5 * time.Minute
 	podPollPeriod = 2 * time.Second
-)
-
-func printHeader(title string) {
-	fmt.Printf("\r\n=== %s ===\n", title)
-}
-
-func printFooter() {
-	fmt.Printf("\r\n================\n")
-}
-
-func printStep(format string, args ...interface{}) {
-	fmt.Printf("\r➡️  %s\n", fmt.Sprintf(format, args...))
-}
 
-func printSuccess(format string, args ...interface{}) {
-	fmt.Printf("\r✅ %s\n", fmt.Sprintf(format, args...))
-}
-
-func printMsg(format string, args ...interface{}) {
-	fmt.Printf("\r%s\n", fmt.Sprintf(format, args...))
-}
+	resizeTimeout    = 5 * time.Minute
+	resizePollPeriod = 2 * time.Second
+)
 
 type VolumeInfo struct {
 	Name         string
@@ -42,21 +29,28 @@ type VolumeInfo struct {
 	Size         string
 	Status       string
 	StorageClass string
-	AccessModes  []corev1.PersistentVolumeAccessMode
-	VolumeMode   *corev1.PersistentVolumeMode
-	Labels       map[string]string
-	Annotations  map[string]string
+	// VolumeName is the PV the claim is bound to
+	VolumeName  string
+	AccessModes []corev1.PersistentVolumeAccessMode
+	VolumeMode  *corev1.PersistentVolumeMode
+	Labels      map[string]string
+	Annotations map[string]string
+	// Usage is nil when no kubelet reported the volume, e.g. when it is not
+	// mounted by a running pod
+	Usage *VolumeUsage
 }
 
 type VolumeController struct {
-	clientset *kubernetes.Clientset
-	config    *rest.Config
+	clientset     kubernetes.Interface
+	dynamicClient dynamic.Interface
+	config        *rest.Config
 }
 
-func NewVolumeController(clientset *kubernetes.Clientset, config *rest.Config) *VolumeController {
+func NewVolumeController(clientset kubernetes.Interface, dynamicClient dynamic.Interface, config *rest.Config) *VolumeController {
 	return &VolumeController{
-		clientset: clientset,
-		config:    config,
+		clientset:     clientset,
+		dynamicClient: dynamicClient,
+		config:        config,
 	}
 }
 
@@ -75,6 +69,7 @@ func (vc *VolumeController) ListVolumes() ([]VolumeInfo, error) {
 			Size:         pvc.Spec.Resources.Requests.Storage().String(),
 			Status:       string(pvc.Status.Phase),
 			StorageClass: getStorageClassName(pvc.Spec.StorageClassName),
+			VolumeName:   pvc.Spec.VolumeName,
 			AccessModes:  pvc.Spec.AccessModes,
 			VolumeMode:   pvc.Spec.VolumeMode,
 			Labels:       pvc.Labels,
@@ -114,18 +109,18 @@ func (vc *VolumeController) waitForPodTermination(ctx context.Context, namespace
 	defer cancel()
 
 	for {
-		select {
-		case <-timeoutCtx.Done():
-			return fmt.Errorf("timeout waiting for pods to terminate")
-		default:
-			podsUsingPVC, err := vc.findPodsUsingPVC(namespace, pvcName)
-			if err != nil {
-				return fmt.Errorf("failed to check if pods are terminated: %v", err)
-			}
-			if len(podsUsingPVC) == 0 {
-				return nil
+		podsUsingPVC, err := vc.findPodsUsingPVC(namespace, pvcName)
+		if err != nil {
+			return fmt.Errorf("failed to check if pods are terminated: %v", err)
+		}
+		if len(podsUsingPVC) == 0 {
+			return nil
+		}
+		if err := sleepContext(timeoutCtx, podPollPeriod); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
-			time.Sleep(podPollPeriod)
+			return fmt.Errorf("timeout waiting for pods to terminate")
 		}
 	}
 }
@@ -142,90 +137,109 @@ func (vc *VolumeController) deletePod(ctx context.Context, namespace, name strin
 	defer cancel()
 
 	for {
-		select {
-		case <-timeoutCtx.Done():
+		_, err := vc.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return nil // Pod is deleted
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to get pod %s: %v", name, err)
+		}
+		if err := sleepContext(timeoutCtx, time.Second); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			// Force delete if graceful deletion takes too long
 			gracePeriod := int64(0)
 			return vc.clientset.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{
 				GracePeriodSeconds: &gracePeriod,
 			})
-		default:
-			_, err := vc.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
-			if err != nil {
-				return nil // Pod is deleted
-			}
-			time.Sleep(time.Second)
 		}
 	}
 }
 
-func (vc *VolumeController) waitForPVCDeletion(ctx context.Context, namespace, name string) error {
-	printStep("Waiting for PVC %s to be deleted...", name)
+func (vc *VolumeController) waitForPVCDeletion(ctx context.Context, namespace, name string, progress ProgressFunc) error {
+	progress.step("Waiting for PVC %s to be deleted...", name)
 	for i := 0; i < 30; i++ {
 		_, err := vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
-		if err != nil {
+		if errors.IsNotFound(err) {
 			// PVC is deleted
 			return nil
 		}
-		time.Sleep(time.Second)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to get PVC %s: %v", name, err)
+		}
+		if err := sleepContext(ctx, time.Second); err != nil {
+			return err
+		}
+	}
+	if d, err := vc.DiagnosePVC(namespace, name); err == nil {
+		return fmt.Errorf("timeout waiting for PVC %s to be deleted: %s", name, d.Summary())
 	}
 	return fmt.Errorf("timeout waiting for PVC %s to be deleted", name)
 }
 
-func (vc *VolumeController) DeleteVolume(namespace, name string) error {
-	ctx := context.TODO()
-
-	printHeader("Volume Delete Operation")
-	printMsg("PVC: %s/%s", namespace, name)
-	printFooter()
+// DeleteVolumeOptions controls the behaviour of DeleteVolume
+type DeleteVolumeOptions struct {
+	// SkipRestore keeps the workloads that mounted the PVC paused after the
+	// PVC is deleted. They can be brought back later with RestoreWorkloads
+	SkipRestore bool
+}
 
-	// First check if PVC exists
-	pvc, err := vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+// DeleteVolume plans and executes the deletion of a PVC
+func (vc *VolumeController) DeleteVolume(ctx context.Context, namespace, name string, opts DeleteVolumeOptions, progress ProgressFunc) error {
+	plan, err := vc.PlanDeleteVolume(namespace, name)
 	if err != nil {
-		return fmt.Errorf("failed to get PVC: %v", err)
+		return err
 	}
+	return vc.ExecuteDeletePlan(ctx, plan, opts, progress)
+}
 
-	printStep("Found PVC: %s (Status: %s)", name, pvc.Status.Phase)
+// ExecuteDeletePlan pauses the workloads of a plan, deletes its PVC and
+// restores the workloads afterwards. If any step fails, the workloads paused
+// so far are restored. The plan is refused if the PVC or the workloads using
+// it changed since it was computed. Cancelling ctx stops the operation and
+// rolls it back
+func (vc *VolumeController) ExecuteDeletePlan(ctx context.Context, plan *VolumeDeletePlan, opts DeleteVolumeOptions, progress ProgressFunc) (err error) {
+	namespace, name := plan.Namespace, plan.PVCName
 
-	// Find deployments using the PVC
-	deployments, err := vc.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to list deployments: %v", err)
+	if err := plan.Executable(); err != nil {
+		return err
 	}
 
-	// Track deployments using this PVC
-	var deploymentsToRestore []struct {
-		name     string
-		replicas int32
+	progress.step("Verifying plan for PVC %s", name)
+	if err := vc.verifyDeletePlan(ctx, plan); err != nil {
+		return err
 	}
 
-	for _, deployment := range deployments.Items {
-		usePVC := false
-		for _, volume := range deployment.Spec.Template.Spec.Volumes {
-			if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == name {
-				usePVC = true
-				break
-			}
+	// Track workloads paused for this PVC so they can be restored
+	var quiesced []Workload
+	defer func() {
+		if err == nil || len(quiesced) == 0 {
+			return
 		}
+		progress.step("Rolling back: restoring %d workload(s)", len(quiesced))
+		// The rollback must run even when the operation was cancelled
+		rollbackCtx := context.WithoutCancel(ctx)
+		if restoreErr := vc.restoreWorkloads(rollbackCtx, namespace, quiesced, progress); restoreErr != nil {
+			err = fmt.Errorf("%v (rollback failed: %v)", err, restoreErr)
+		}
+	}()
 
-		if usePVC {
-			printStep("Found deployment %s using the PVC", deployment.Name)
-			deploymentsToRestore = append(deploymentsToRestore, struct {
-				name     string
-				replicas int32
-			}{
-				name:     deployment.Name,
-				replicas: *deployment.Spec.Replicas,
-			})
-
-			// Scale down the deployment
-			printStep("Scaling down deployment %s", deployment.Name)
-			var replicas int32 = 0
-			deployment.Spec.Replicas = &replicas
-			_, err := vc.clientset.AppsV1().Deployments(namespace).Update(ctx, &deployment, metav1.UpdateOptions{})
-			if err != nil {
-				return fmt.Errorf("failed to scale down deployment %s: %v", deployment.Name, err)
-			}
+	for _, workload := range plan.Workloads {
+		if err := vc.quiesceWorkload(ctx, namespace, workload, name, progress); err != nil {
+			return err
+		}
+		if workload.Restorable() {
+			quiesced = append(quiesced, workload)
+		}
+		if err := vc.deleteActiveJobs(ctx, namespace, workload, progress); err != nil {
+			return err
 		}
 	}
 
@@ -236,38 +250,186 @@ func (vc *VolumeController) DeleteVolume(namespace, name string) error {
 	}
 
 	if len(podsUsingPVC) > 0 {
-		printStep("Found %d pods still using the PVC", len(podsUsingPVC))
+		progress.step("Found %d pods still using the PVC", len(podsUsingPVC))
 		for _, pod := range podsUsingPVC {
-			printMsg("    └─ Pod: %s", pod.Name)
+			progress.detail("Pod: %s", pod.Name)
+			// Nothing recreates bare pods, so they are deleted directly
+			if isBarePod(&pod) {
+				if err := vc.deletePod(ctx, namespace, pod.Name); err != nil {
+					return fmt.Errorf("failed to delete pod %s: %v", pod.Name, err)
+				}
+			}
 		}
 
 		// Wait for all pods to terminate
-		printStep("Waiting for pods to terminate...")
+		progress.step("Waiting for pods to terminate...")
 		if err := vc.waitForPodTermination(ctx, namespace, name); err != nil {
 			return fmt.Errorf("failed waiting for pods to terminate: %v", err)
 		}
-		printSuccess("All pods terminated")
+		progress.success("All pods terminated")
 	}
 
 	// Delete the PVC
-	printStep("Deleting PVC %s...", name)
-	err = vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	progress.step("Deleting PVC %s...", name)
+	err = vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &plan.PVCUID},
+	})
 	if err != nil {
 		// Check if it's already gone
 		_, getErr := vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
-		if getErr != nil {
-			printSuccess("PVC already deleted")
-			return nil
+		if !errors.IsNotFound(getErr) {
+			return fmt.Errorf("failed to delete PVC: %v", err)
+		}
+		progress.success("PVC already deleted")
+	} else if err := vc.waitForPVCDeletion(ctx, namespace, name, progress); err != nil {
+		return fmt.Errorf("failed while waiting for PVC deletion: %v", err)
+	}
+
+	if opts.SkipRestore {
+		if len(quiesced) > 0 {
+			progress.detail("Keeping %d workload(s) paused", len(quiesced))
+		}
+	} else if len(quiesced) > 0 {
+		progress.step("Restoring %d workload(s)", len(quiesced))
+		if err := vc.restoreWorkloads(ctx, namespace, quiesced, progress); err != nil {
+			// The PVC is gone, rolling back would only repeat the restore
+			quiesced = nil
+			return fmt.Errorf("volume deleted but failed to restore workloads: %v", err)
 		}
-		return fmt.Errorf("failed to delete PVC: %v", err)
+		progress.success("Workloads restored")
 	}
 
-	// Wait for PVC to be deleted
-	if err := vc.waitForPVCDeletion(ctx, namespace, name); err != nil {
-		return fmt.Errorf("failed while waiting for PVC deletion: %v", err)
+	progress.success("Volume deleted successfully")
+	return nil
+}
+
+// ResizeVolume expands a PVC to the given quantity and waits until the new
+// capacity is reported in the PVC status
+func (vc *VolumeController) ResizeVolume(ctx context.Context, namespace, name, quantity string, progress ProgressFunc) error {
+	newSize, err := resource.ParseQuantity(quantity)
+	if err != nil {
+		return fmt.Errorf("invalid size %q: %v", quantity, err)
+	}
+
+	pvc, err := vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get PVC: %v", err)
+	}
+
+	currentSize := pvc.Spec.Resources.Requests.Storage()
+	switch newSize.Cmp(*currentSize) {
+	case -1:
+		return fmt.Errorf("cannot shrink PVC from %s to %s", currentSize.String(), newSize.String())
+	case 0:
+		return fmt.Errorf("PVC is already %s", currentSize.String())
+	}
+
+	progress.step("Checking StorageClass of PVC %s", name)
+	if err := vc.checkVolumeExpansion(ctx, pvc); err != nil {
+		return err
+	}
+
+	progress.step("Requesting new size %s (current: %s)", newSize.String(), currentSize.String())
+	patch := fmt.Sprintf(`{"spec":{"resources":{"requests":{"storage":%q}}}}`, newSize.String())
+	_, err = vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to patch PVC: %v", err)
+	}
+
+	if err := vc.waitForResize(ctx, namespace, name, newSize, progress); err != nil {
+		return err
+	}
+
+	progress.success("Volume resized successfully")
+	return nil
+}
+
+func (vc *VolumeController) checkVolumeExpansion(ctx context.Context, pvc *corev1.PersistentVolumeClaim) error {
+	scName := getStorageClassName(pvc.Spec.StorageClassName)
+	if scName == "" {
+		scName = pvc.Annotations[corev1.BetaStorageClassAnnotation]
+	}
+	if scName == "" {
+		return fmt.Errorf("PVC %s has no StorageClass, volume expansion is not supported", pvc.Name)
 	}
 
-	printSuccess("Volume deleted successfully")
-	printFooter()
+	sc, err := vc.clientset.StorageV1().StorageClasses().Get(ctx, scName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get StorageClass %s: %v", scName, err)
+	}
+	if sc.AllowVolumeExpansion == nil || !*sc.AllowVolumeExpansion {
+		return fmt.Errorf("StorageClass %s does not allow volume expansion", scName)
+	}
 	return nil
 }
+
+func (vc *VolumeController) waitForResize(ctx context.Context, namespace, name string, size resource.Quantity, progress ProgressFunc) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, resizeTimeout)
+	defer cancel()
+
+	var lastCondition corev1.PersistentVolumeClaimConditionType
+	for {
+		select {
+		case <-timeoutCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if lastCondition == corev1.PersistentVolumeClaimFileSystemResizePending {
+				return fmt.Errorf("timeout waiting for file system resize of PVC %s", name)
+			}
+			return fmt.Errorf("timeout waiting for PVC %s to reach %s", name, size.String())
+		default:
+			pvc, err := vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(timeoutCtx, name, metav1.GetOptions{})
+			if err != nil {
+				if timeoutCtx.Err() != nil {
+					// Cancelled or timed out during the request, reported
+					// by the Done case
+					continue
+				}
+				return fmt.Errorf("failed to get PVC: %v", err)
+			}
+
+			if capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok && capacity.Cmp(size) >= 0 {
+				return nil
+			}
+
+			condition := getResizeCondition(pvc)
+			if condition != lastCondition {
+				switch condition {
+				case corev1.PersistentVolumeClaimResizing:
+					progress.step("Volume is being resized by the storage provider...")
+				case corev1.PersistentVolumeClaimFileSystemResizePending:
+					progress.step("Waiting for file system resize on the node...")
+				}
+				lastCondition = condition
+			}
+
+			// The file system is only resized once a pod mounts the volume,
+			// so there is nothing left to wait for without one
+			if condition == corev1.PersistentVolumeClaimFileSystemResizePending {
+				podsUsingPVC, err := vc.findPodsUsingPVC(namespace, name)
+				if err != nil {
+					return fmt.Errorf("failed to check for pods using PVC: %v", err)
+				}
+				if len(podsUsingPVC) == 0 {
+					progress.detail("No pod mounts the PVC, file system will be resized on next mount")
+					return nil
+				}
+			}
+			_ = sleepContext(timeoutCtx, resizePollPeriod)
+		}
+	}
+}
+
+func getResizeCondition(pvc *corev1.PersistentVolumeClaim) corev1.PersistentVolumeClaimConditionType {
+	for _, condition := range pvc.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case corev1.PersistentVolumeClaimResizing, corev1.PersistentVolumeClaimFileSystemResizePending:
+			return condition.Type
+		}
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newTestCronJob returns a CronJob mounting pvcName, with an active Job
+func newTestCronJob(name, pvcName string) *batchv1.CronJob {
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "0 * * * *",
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Volumes: []corev1.Volume{{
+								Name: "data",
+								VolumeSource: corev1.VolumeSource{
+									PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvcName},
+								},
+							}},
+						},
+					},
+				},
+			},
+		},
+		Status: batchv1.CronJobStatus{
+			Active: []corev1.ObjectReference{{Name: name + "-1", Namespace: "default"}},
+		},
+	}
+}
+
+// newDeletePlanController returns a controller whose cluster holds the PVC
+// data used by the CronJob backup, and the plan to delete data
+func newDeletePlanController(t *testing.T) (*VolumeController, *fake.Clientset, *VolumeDeletePlan) {
+	t.Helper()
+	pvc := newTestPVC("data", "fast")
+	pvc.UID = "data-uid"
+	clientset := fake.NewSimpleClientset(
+		pvc,
+		newTestCronJob("backup", "data"),
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "backup-1", Namespace: "default"}},
+	)
+	vc := NewVolumeController(clientset, newSnapshotDynamicClient(), nil)
+	plan, err := vc.PlanDeleteVolume("default", "data")
+	if err != nil {
+		t.Fatalf("PlanDeleteVolume: %v", err)
+	}
+	if len(plan.Workloads) != 1 || plan.Workloads[0].Kind != KindCronJob {
+		t.Fatalf("plan workloads = %v, want the CronJob backup", plan.Workloads)
+	}
+	return vc, clientset, plan
+}
+
+func forbidden(resource string) error {
+	return errors.NewForbidden(schema.GroupResource{Resource: resource}, "", nil)
+}
+
+func assertCronJobRestored(t *testing.T, clientset *fake.Clientset) {
+	t.Helper()
+	cj, err := clientset.BatchV1().CronJobs("default").Get(context.Background(), "backup", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get cronjob: %v", err)
+	}
+	if cj.Spec.Suspend == nil || *cj.Spec.Suspend {
+		t.Error("cronjob left suspended")
+	}
+	if _, ok := cj.Annotations[quiescedForAnnotation]; ok {
+		t.Error("cronjob still marked paused")
+	}
+}
+
+func TestExecuteDeletePlanRestoresCronJobWhenJobDeleteFails(t *testing.T) {
+	vc, clientset, plan := newDeletePlanController(t)
+	clientset.PrependReactor("delete", "jobs", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, forbidden("jobs")
+	})
+
+	err := vc.ExecuteDeletePlan(context.Background(), plan, DeleteVolumeOptions{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "backup-1") {
+		t.Fatalf("err = %v, want the failed job deletion reported", err)
+	}
+	assertCronJobRestored(t, clientset)
+	if _, err := clientset.CoreV1().PersistentVolumeClaims("default").Get(context.Background(), "data", metav1.GetOptions{}); err != nil {
+		t.Errorf("PVC deleted after a failed pause: %v", err)
+	}
+}
+
+func TestExecuteDeletePlan(t *testing.T) {
+	vc, clientset, plan := newDeletePlanController(t)
+
+	if err := vc.ExecuteDeletePlan(context.Background(), plan, DeleteVolumeOptions{}, nil); err != nil {
+		t.Fatalf("ExecuteDeletePlan: %v", err)
+	}
+	if _, err := clientset.CoreV1().PersistentVolumeClaims("default").Get(context.Background(), "data", metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("PVC not deleted: %v", err)
+	}
+	if _, err := clientset.BatchV1().Jobs("default").Get(context.Background(), "backup-1", metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("active job not deleted: %v", err)
+	}
+	assertCronJobRestored(t, clientset)
+}
+
+func TestExecuteDeletePlanPVCDeleteFails(t *testing.T) {
+	pvcs := corev1.Resource("persistentvolumeclaims")
+	tests := []struct {
+		name      string
+		deleteErr error
+		getErr    error
+		wantErr   bool
+	}{
+		// A PVC recreated since the plan fails the UID precondition
+		{"conflict", errors.NewConflict(pvcs, "data", nil), nil, true},
+		{"lookup fails", forbidden("persistentvolumeclaims"), forbidden("persistentvolumeclaims"), true},
+		{"already deleted", errors.NewNotFound(pvcs, "data"), errors.NewNotFound(pvcs, "data"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vc, clientset, plan := newDeletePlanController(t)
+			clientset.PrependReactor("delete", "persistentvolumeclaims", func(k8stesting.Action) (bool, runtime.Object, error) {
+				// Only the lookup after the failed delete sees getErr
+				if tt.getErr != nil {
+					clientset.PrependReactor("get", "persistentvolumeclaims", func(k8stesting.Action) (bool, runtime.Object, error) {
+						return true, nil, tt.getErr
+					})
+				}
+				return true, nil, tt.deleteErr
+			})
+
+			err := vc.ExecuteDeletePlan(context.Background(), plan, DeleteVolumeOptions{}, nil)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.deleteErr.Error()) {
+					t.Errorf("err = %v, want the delete error %v", err, tt.deleteErr)
+				}
+			} else if err != nil {
+				t.Errorf("ExecuteDeletePlan: %v", err)
+			}
+			assertCronJobRestored(t, clientset)
+		})
+	}
+}
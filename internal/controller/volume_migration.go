@@ -0,0 +1,491 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// migratedToAnnotation is set on a migrated PVC and names the PVC its
+	// data was copied to
+	migratedToAnnotation = "kubegreen.io/migrated-to"
+	// migratedFromAnnotation is set on the target PVC of a migration
+	migratedFromAnnotation = "kubegreen.io/migrated-from"
+
+	copyJobImage   = "busybox:1.36"
+	copyTimeout    = 6 * time.Hour
+	copyPollPeriod = 5 * time.Second
+)
+
+// copyScript copies /source into /target, then writes the byte count of
+// both trees to the termination log so they can be compared
+const copyScript = `set -e
+cp -a /source/. /target/
+sync
+count() { find "$1" -type f -exec stat -c %s {} + | awk '{s+=$1} END {print s+0}'; }
+echo "$(count /source) $(count /target)" > /dev/termination-log
+`
+
+// VolumeMigration is the result of MigrateVolume. The source PVC is kept
+// until FinalizeMigration deletes it
+type VolumeMigration struct {
+	Namespace   string
+	SourcePVC   string
+	TargetPVC   string
+	TargetClass string
+	CopiedBytes int64
+	// Workloads now mount the target PVC
+	Workloads []Workload
+}
+
+// MigrateVolume copies a PVC into a new PVC of another StorageClass. The
+// workloads using the PVC are paused as in DeleteVolume, the data is copied
+// by a Job mounting both claims, the byte counts of both sides are compared
+// and the workloads are repointed to the new PVC before they are restored.
+// The source PVC is kept and marked with the name of its replacement. If
+// any step fails, the workloads are repointed back and restored and the new
+// PVC is deleted
+func (vc *VolumeController) MigrateVolume(ctx context.Context, namespace, name, targetClass string, progress ProgressFunc) (migration *VolumeMigration, err error) {
+	pvcs := vc.clientset.CoreV1().PersistentVolumeClaims(namespace)
+
+	progress.step("Checking PVC %s", name)
+	source, err := pvcs.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PVC: %v", err)
+	}
+	if target := source.Annotations[migratedToAnnotation]; target != "" {
+		return nil, fmt.Errorf("PVC %s was already migrated to %s, finalize that migration first", name, target)
+	}
+	if source.Spec.VolumeMode != nil && *source.Spec.VolumeMode == corev1.PersistentVolumeBlock {
+		return nil, fmt.Errorf("PVC %s is a block volume, only file system volumes can be migrated", name)
+	}
+	sourceClass := getStorageClassName(source.Spec.StorageClassName)
+	if sourceClass == "" {
+		sourceClass = source.Annotations[corev1.BetaStorageClassAnnotation]
+	}
+	if sourceClass == targetClass {
+		return nil, fmt.Errorf("PVC %s already uses StorageClass %s", name, targetClass)
+	}
+	if _, err := vc.clientset.StorageV1().StorageClasses().Get(ctx, targetClass, metav1.GetOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to get StorageClass %s: %v", targetClass, err)
+	}
+
+	targetName := migrationTargetName(name, targetClass)
+	if _, err := pvcs.Get(ctx, targetName, metav1.GetOptions{}); err == nil {
+		return nil, fmt.Errorf("PVC %s already exists", targetName)
+	} else if !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to check for PVC %s: %v", targetName, err)
+	}
+
+	progress.step("Finding workloads using PVC %s", name)
+	workloads, err := vc.findPVCConsumers(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := vc.checkMigratable(ctx, namespace, name, workloads); err != nil {
+		return nil, err
+	}
+	for _, workload := range workloads {
+		progress.detail("%s", workload)
+	}
+
+	migration = &VolumeMigration{
+		Namespace:   namespace,
+		SourcePVC:   name,
+		TargetPVC:   targetName,
+		TargetClass: targetClass,
+	}
+
+	var (
+		createdTarget bool
+		quiesced      []Workload
+		repointed     []Workload
+	)
+	defer func() {
+		if err == nil || (!createdTarget && len(quiesced) == 0 && len(repointed) == 0) {
+			return
+		}
+		progress.step("Rolling back migration of PVC %s", name)
+		// The rollback must run even when the operation was cancelled
+		rollbackCtx := context.WithoutCancel(ctx)
+		var errs []string
+		for _, workload := range repointed {
+			if repointErr := vc.repointWorkload(rollbackCtx, namespace, workload, targetName, name); repointErr != nil {
+				errs = append(errs, repointErr.Error())
+			}
+		}
+		if restoreErr := vc.restoreWorkloads(rollbackCtx, namespace, quiesced, progress); restoreErr != nil {
+			errs = append(errs, restoreErr.Error())
+		}
+		if createdTarget && len(errs) == 0 {
+			progress.detail("Deleting PVC %s", targetName)
+			deleteErr := pvcs.Delete(rollbackCtx, targetName, metav1.DeleteOptions{})
+			if deleteErr != nil && !errors.IsNotFound(deleteErr) {
+				errs = append(errs, fmt.Sprintf("failed to delete PVC %s: %v", targetName, deleteErr))
+			}
+		}
+		if len(errs) > 0 {
+			err = fmt.Errorf("%v (rollback failed: %s)", err, strings.Join(errs, "; "))
+		}
+	}()
+
+	progress.step("Creating PVC %s (StorageClass %s, %s)", targetName, targetClass, source.Spec.Resources.Requests.Storage().String())
+	target := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        targetName,
+			Namespace:   namespace,
+			Labels:      source.Labels,
+			Annotations: map[string]string{migratedFromAnnotation: name},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      source.Spec.AccessModes,
+			StorageClassName: &targetClass,
+			VolumeMode:       source.Spec.VolumeMode,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: *source.Spec.Resources.Requests.Storage()},
+			},
+		},
+	}
+	if _, err := pvcs.Create(ctx, target, metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to create PVC %s: %v", targetName, err)
+	}
+	createdTarget = true
+
+	for _, workload := range workloads {
+		if err := vc.quiesceWorkload(ctx, namespace, workload, name, progress); err != nil {
+			return nil, err
+		}
+		quiesced = append(quiesced, workload)
+		if err := vc.deleteActiveJobs(ctx, namespace, workload, progress); err != nil {
+			return nil, err
+		}
+	}
+
+	progress.step("Waiting for pods to terminate...")
+	if err := vc.waitForPodTermination(ctx, namespace, name); err != nil {
+		return nil, fmt.Errorf("failed waiting for pods to terminate: %v", err)
+	}
+
+	migration.CopiedBytes, err = vc.runCopyJob(ctx, namespace, name, targetName, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	progress.step("Repointing %d workload(s) to PVC %s", len(workloads), targetName)
+	for _, workload := range workloads {
+		if err := vc.repointWorkload(ctx, namespace, workload, name, targetName); err != nil {
+			return nil, err
+		}
+		repointed = append(repointed, workload)
+		progress.detail("Repointed %s", workload)
+	}
+	migration.Workloads = workloads
+
+	progress.step("Marking PVC %s as migrated", name)
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, migratedToAnnotation, targetName)
+	if _, err := pvcs.Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to annotate PVC %s: %v", name, err)
+	}
+
+	if len(quiesced) > 0 {
+		progress.step("Restoring %d workload(s)", len(quiesced))
+		if err := vc.restoreWorkloads(ctx, namespace, quiesced, progress); err != nil {
+			// The data is copied and the workloads repointed, rolling back
+			// would throw that away
+			quiesced, repointed, createdTarget = nil, nil, false
+			return migration, fmt.Errorf("volume migrated but failed to restore workloads: %v", err)
+		}
+	}
+
+	progress.success("PVC %s migrated to %s (%s copied), %s is kept until the migration is finalized",
+		name, targetName, formatCopiedBytes(migration.CopiedBytes), name)
+	return migration, nil
+}
+
+// MigratedTo returns the PVC the data of the volume was migrated to, empty
+// when it was not migrated
+func (v *VolumeInfo) MigratedTo() string {
+	return v.Annotations[migratedToAnnotation]
+}
+
+// FinalizeMigration deletes the source PVC of a migration once nothing uses
+// it anymore
+func (vc *VolumeController) FinalizeMigration(ctx context.Context, namespace, name string, progress ProgressFunc) error {
+	progress.step("Checking migration of PVC %s", name)
+	source, err := vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get PVC: %v", err)
+	}
+	targetName := source.Annotations[migratedToAnnotation]
+	if targetName == "" {
+		return fmt.Errorf("PVC %s was not migrated", name)
+	}
+	if _, err := vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, targetName, metav1.GetOptions{}); err != nil {
+		return fmt.Errorf("failed to get migrated PVC %s: %v", targetName, err)
+	}
+
+	plan, err := vc.PlanDeleteVolume(namespace, name)
+	if err != nil {
+		return err
+	}
+	if len(plan.Workloads) > 0 || len(plan.Pods) > 0 {
+		return fmt.Errorf("PVC %s is still in use (%s), it will not be deleted", name, joinWorkloads(plan.Workloads))
+	}
+	return vc.ExecuteDeletePlan(ctx, plan, DeleteVolumeOptions{}, progress)
+}
+
+// checkMigratable refuses migrations whose consumers cannot be repointed
+func (vc *VolumeController) checkMigratable(ctx context.Context, namespace, pvcName string, workloads []Workload) error {
+	for _, workload := range workloads {
+		switch workload.Kind {
+		case KindDeployment, KindReplicaSet, KindDaemonSet, KindCronJob:
+		case KindStatefulSet:
+			sts, err := vc.clientset.AppsV1().StatefulSets(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get statefulset %s: %v", workload.Name, err)
+			}
+			if statefulSetOwnsClaim(sts, pvcName) {
+				return fmt.Errorf("PVC %s belongs to the volumeClaimTemplates of %s and cannot be repointed", pvcName, workload)
+			}
+		case KindJob:
+			return fmt.Errorf("%s mounts the PVC, wait for it to finish before migrating", workload)
+		default:
+			return fmt.Errorf("%s mounts the PVC and cannot be repointed", workload)
+		}
+	}
+
+	pods, err := vc.findPodsUsingPVC(namespace, pvcName)
+	if err != nil {
+		return fmt.Errorf("failed to check for pods using PVC: %v", err)
+	}
+	for _, pod := range pods {
+		if isBarePod(&pod) {
+			return fmt.Errorf("bare pod %s mounts the PVC and cannot be repointed", pod.Name)
+		}
+	}
+	return nil
+}
+
+// runCopyJob copies the source claim into the target claim and returns the
+// number of copied bytes once both sides report the same count
+func (vc *VolumeController) runCopyJob(ctx context.Context, namespace, source, target string, progress ProgressFunc) (int64, error) {
+	jobs := vc.clientset.BatchV1().Jobs(namespace)
+	name := strings.TrimRight(truncate("kubegreen-migrate-"+source, 63), "-.")
+
+	var backoffLimit int32 = 0
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "kubegreen"},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "copy",
+						Image:   copyJobImage,
+						Command: []string{"sh", "-c", copyScript},
+						VolumeMounts: []corev1.VolumeMount{
+							{Name: "source", MountPath: "/source", ReadOnly: true},
+							{Name: "target", MountPath: "/target"},
+						},
+						TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+					}},
+					Volumes: []corev1.Volume{
+						{Name: "source", VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: source, ReadOnly: true},
+						}},
+						{Name: "target", VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: target},
+						}},
+					},
+				},
+			},
+		},
+	}
+
+	progress.step("Copying data from %s to %s (job %s)", source, target, name)
+	if _, err := jobs.Create(ctx, job, metav1.CreateOptions{}); err != nil {
+		return 0, fmt.Errorf("failed to create copy job: %v", err)
+	}
+	defer func() {
+		// The job is removed even when the operation was cancelled
+		cleanupCtx := context.WithoutCancel(ctx)
+		if err := vc.deleteJob(cleanupCtx, namespace, name); err != nil {
+			progress.detail("Failed to delete copy job %s: %v", name, err)
+		}
+	}()
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, copyTimeout)
+	defer cancel()
+	for {
+		current, err := jobs.Get(timeoutCtx, name, metav1.GetOptions{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			return 0, fmt.Errorf("failed to get copy job: %v", err)
+		}
+		if current.Status.Succeeded > 0 || current.Status.Failed > 0 {
+			break
+		}
+		if err := sleepContext(timeoutCtx, copyPollPeriod); err != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			return 0, fmt.Errorf("timeout waiting for copy job %s", name)
+		}
+	}
+
+	message, succeeded, err := vc.copyJobResult(ctx, namespace, name)
+	if err != nil {
+		return 0, err
+	}
+	if !succeeded {
+		return 0, fmt.Errorf("copy job %s failed: %s", name, message)
+	}
+
+	progress.step("Verifying copied data")
+	var sourceBytes, targetBytes int64
+	if _, err := fmt.Sscanf(message, "%d %d", &sourceBytes, &targetBytes); err != nil {
+		return 0, fmt.Errorf("unexpected copy job result %q", message)
+	}
+	progress.detail("Source: %s, target: %s", formatCopiedBytes(sourceBytes), formatCopiedBytes(targetBytes))
+	if sourceBytes != targetBytes {
+		return 0, fmt.Errorf("byte counts differ after copy: source %d, target %d", sourceBytes, targetBytes)
+	}
+	return sourceBytes, nil
+}
+
+// copyJobResult returns the termination message of the copy job's pod and
+// whether the copy succeeded
+func (vc *VolumeController) copyJobResult(ctx context.Context, namespace, jobName string) (string, bool, error) {
+	pods, err := vc.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "job-name=" + jobName,
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("failed to list copy job pods: %v", err)
+	}
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if terminated := status.State.Terminated; terminated != nil {
+				message := strings.TrimSpace(terminated.Message)
+				if message == "" {
+					message = terminated.Reason
+				}
+				return message, terminated.ExitCode == 0, nil
+			}
+		}
+	}
+	return "", false, fmt.Errorf("copy job %s has no terminated pod", jobName)
+}
+
+// repointWorkload replaces the claim from with the claim to in the pod
+// template of a workload
+func (vc *VolumeController) repointWorkload(ctx context.Context, namespace string, workload Workload, from, to string) error {
+	apps := vc.clientset.AppsV1()
+	batch := vc.clientset.BatchV1()
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		switch workload.Kind {
+		case KindDeployment:
+			d, err := apps.Deployments(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if !repointPodSpec(&d.Spec.Template.Spec, from, to) {
+				return nil
+			}
+			_, err = apps.Deployments(namespace).Update(ctx, d, metav1.UpdateOptions{})
+			return err
+		case KindStatefulSet:
+			sts, err := apps.StatefulSets(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if !repointPodSpec(&sts.Spec.Template.Spec, from, to) {
+				return nil
+			}
+			_, err = apps.StatefulSets(namespace).Update(ctx, sts, metav1.UpdateOptions{})
+			return err
+		case KindReplicaSet:
+			rs, err := apps.ReplicaSets(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if !repointPodSpec(&rs.Spec.Template.Spec, from, to) {
+				return nil
+			}
+			_, err = apps.ReplicaSets(namespace).Update(ctx, rs, metav1.UpdateOptions{})
+			return err
+		case KindDaemonSet:
+			ds, err := apps.DaemonSets(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if !repointPodSpec(&ds.Spec.Template.Spec, from, to) {
+				return nil
+			}
+			_, err = apps.DaemonSets(namespace).Update(ctx, ds, metav1.UpdateOptions{})
+			return err
+		case KindCronJob:
+			cj, err := batch.CronJobs(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if !repointPodSpec(&cj.Spec.JobTemplate.Spec.Template.Spec, from, to) {
+				return nil
+			}
+			_, err = batch.CronJobs(namespace).Update(ctx, cj, metav1.UpdateOptions{})
+			return err
+		}
+		return fmt.Errorf("unsupported workload kind")
+	})
+	if err != nil {
+		return fmt.Errorf("failed to repoint %s to PVC %s: %v", workload, to, err)
+	}
+	return nil
+}
+
+// repointPodSpec replaces the claim from with the claim to and reports
+// whether the spec changed
+func repointPodSpec(spec *corev1.PodSpec, from, to string) bool {
+	changed := false
+	for i := range spec.Volumes {
+		claim := spec.Volumes[i].PersistentVolumeClaim
+		if claim != nil && claim.ClaimName == from {
+			claim.ClaimName = to
+			changed = true
+		}
+	}
+	return changed
+}
+
+// migrationTargetName returns the name of the PVC a migration copies into
+func migrationTargetName(name, class string) string {
+	return strings.TrimRight(truncate(name+"-"+class, 253), "-.")
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+func formatCopiedBytes(bytes int64) string {
+	return resource.NewQuantity(bytes, resource.BinarySI).String()
+}
@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PlannedPod is a pod that will be terminated by a volume operation
+type PlannedPod struct {
+	Name string
+	// Owner is the workload controlling the pod, empty for bare pods
+	Owner string
+}
+
+// VolumeDeletePlan describes everything DeleteVolume does for a PVC. It is
+// computed without modifying the cluster and executed by ExecuteDeletePlan
+type VolumeDeletePlan struct {
+	Namespace string
+	PVCName   string
+	PVCUID    types.UID
+	Phase     corev1.PersistentVolumeClaimPhase
+	Size      string
+
+	// Workloads are paused before the PVC is deleted
+	Workloads []Workload
+	// Pods currently mounting the PVC
+	Pods []PlannedPod
+
+	PVName        string
+	ReclaimPolicy corev1.PersistentVolumeReclaimPolicy
+	// DataDestroyed is true when deleting the PVC also destroys its data
+	DataDestroyed bool
+
+	Snapshots []SnapshotInfo
+	Warnings  []string
+}
+
+// Executable returns an error if the plan contains steps kubegreen cannot
+// perform
+func (p *VolumeDeletePlan) Executable() error {
+	for _, workload := range p.Workloads {
+		if !workload.Supported() {
+			return fmt.Errorf("cannot pause %s: unsupported workload kind", workload)
+		}
+	}
+	return nil
+}
+
+// PlanDeleteVolume computes what deleting a PVC would do without touching
+// the cluster
+func (vc *VolumeController) PlanDeleteVolume(namespace, name string) (*VolumeDeletePlan, error) {
+	ctx := context.TODO()
+
+	pvc, err := vc.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PVC: %v", err)
+	}
+
+	plan := &VolumeDeletePlan{
+		Namespace: namespace,
+		PVCName:   name,
+		PVCUID:    pvc.UID,
+		Phase:     pvc.Status.Phase,
+		Size:      pvc.Spec.Resources.Requests.Storage().String(),
+	}
+
+	plan.Workloads, err = vc.findPVCConsumers(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, workload := range plan.Workloads {
+		if !workload.Supported() {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s cannot be paused, the plan cannot be executed", workload))
+		} else if !workload.Restorable() {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s will be deleted and not restored", workload))
+		}
+	}
+
+	plan.Pods, err = vc.planPods(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if pvc.Spec.VolumeName != "" {
+		pv, err := vc.clientset.CoreV1().PersistentVolumes().Get(ctx, pvc.Spec.VolumeName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get PV %s: %v", pvc.Spec.VolumeName, err)
+		}
+		plan.PVName = pv.Name
+		plan.ReclaimPolicy = pv.Spec.PersistentVolumeReclaimPolicy
+		plan.DataDestroyed = pv.Spec.PersistentVolumeReclaimPolicy != corev1.PersistentVolumeReclaimRetain
+	}
+
+	plan.Snapshots, err = vc.ListSnapshots(namespace, name)
+	if err != nil {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("could not list snapshots: %v", err))
+	} else if len(plan.Snapshots) == 0 && plan.DataDestroyed {
+		plan.Warnings = append(plan.Warnings, "no snapshot of this PVC exists, its data cannot be recovered")
+	}
+
+	return plan, nil
+}
+
+func (vc *VolumeController) planPods(ctx context.Context, namespace, name string) ([]PlannedPod, error) {
+	pods, err := vc.findPodsUsingPVC(namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for pods using PVC: %v", err)
+	}
+
+	planned := make([]PlannedPod, 0, len(pods))
+	for _, pod := range pods {
+		owner, err := vc.resolvePodController(ctx, &pod)
+		if err != nil {
+			return nil, err
+		}
+		p := PlannedPod{Name: pod.Name}
+		if owner != nil {
+			p.Owner = owner.String()
+		}
+		planned = append(planned, p)
+	}
+	sort.Slice(planned, func(i, j int) bool {
+		return planned[i].Name < planned[j].Name
+	})
+	return planned, nil
+}
+
+// verifyDeletePlan makes sure the PVC and its consumers did not change since
+// the plan was computed, so that what was reviewed is exactly what runs
+func (vc *VolumeController) verifyDeletePlan(ctx context.Context, plan *VolumeDeletePlan) error {
+	pvc, err := vc.clientset.CoreV1().PersistentVolumeClaims(plan.Namespace).Get(ctx, plan.PVCName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get PVC: %v", err)
+	}
+	if pvc.UID != plan.PVCUID {
+		return fmt.Errorf("plan is out of date: PVC %s was recreated", plan.PVCName)
+	}
+
+	workloads, err := vc.findPVCConsumers(ctx, plan.Namespace, plan.PVCName)
+	if err != nil {
+		return err
+	}
+	if !sameWorkloads(workloads, plan.Workloads) {
+		return fmt.Errorf("plan is out of date: workloads using the PVC changed (now: %s)", joinWorkloads(workloads))
+	}
+	return nil
+}
+
+func sameWorkloads(a, b []Workload) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].key() != b[i].key() {
+			return false
+		}
+	}
+	return true
+}
+
+func joinWorkloads(workloads []Workload) string {
+	if len(workloads) == 0 {
+		return "none"
+	}
+	names := make([]string, len(workloads))
+	for i, workload := range workloads {
+		names[i] = workload.String()
+	}
+	return strings.Join(names, ", ")
+}
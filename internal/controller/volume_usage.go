@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	statsSummaryTimeout = 10 * time.Second
+	// statsSummaryWorkers bounds the number of kubelets queried at once
+	statsSummaryWorkers = 8
+)
+
+// VolumeUsage is the filesystem usage of a mounted PVC as reported by the
+// kubelet
+type VolumeUsage struct {
+	UsedBytes      int64
+	AvailableBytes int64
+	CapacityBytes  int64
+	Inodes         int64
+	InodesUsed     int64
+	InodesFree     int64
+	// Node is the node whose kubelet reported the usage
+	Node string
+}
+
+// PercentUsed returns the used share of the volume capacity
+func (u VolumeUsage) PercentUsed() float64 {
+	if u.CapacityBytes == 0 {
+		return 0
+	}
+	return float64(u.UsedBytes) / float64(u.CapacityBytes) * 100
+}
+
+// InodesPercentUsed returns the used share of the volume inodes
+func (u VolumeUsage) InodesPercentUsed() float64 {
+	if u.Inodes == 0 {
+		return 0
+	}
+	return float64(u.InodesUsed) / float64(u.Inodes) * 100
+}
+
+// statsSummary is the subset of the kubelet /stats/summary response we read
+type statsSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Volumes []struct {
+			Name           string  `json:"name"`
+			AvailableBytes *uint64 `json:"availableBytes"`
+			CapacityBytes  *uint64 `json:"capacityBytes"`
+			UsedBytes      *uint64 `json:"usedBytes"`
+			Inodes         *uint64 `json:"inodes"`
+			InodesFree     *uint64 `json:"inodesFree"`
+			InodesUsed     *uint64 `json:"inodesUsed"`
+			PVCRef         *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+// CollectVolumeUsage reads the stats summary of every ready node and returns
+// the usage of each mounted PVC keyed by namespace/name. Nodes whose kubelet
+// cannot be reached are skipped; an error is only returned if no node
+// answered
+func (vc *VolumeController) CollectVolumeUsage(ctx context.Context) (map[string]VolumeUsage, error) {
+	nodes, err := vc.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+
+	var ready []string
+	for _, node := range nodes.Items {
+		if isNodeReady(node) {
+			ready = append(ready, node.Name)
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		usage    = make(map[string]VolumeUsage)
+		answered int
+		lastErr  error
+	)
+	workers := make(chan struct{}, statsSummaryWorkers)
+	for _, name := range ready {
+		wg.Add(1)
+		go func(node string) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+
+			summary, err := vc.getStatsSummary(ctx, node)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				lastErr = err
+				return
+			}
+			answered++
+			mergeVolumeUsage(usage, node, summary)
+		}(name)
+	}
+	wg.Wait()
+
+	if answered == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return usage, nil
+}
+
+// ApplyVolumeUsage sets the Usage of the given volumes from usage collected
+// by CollectVolumeUsage. Volumes without an entry are left unmounted
+func ApplyVolumeUsage(volumes []VolumeInfo, usage map[string]VolumeUsage) {
+	for i := range volumes {
+		volumes[i].Usage = nil
+		if u, ok := usage[volumes[i].Namespace+"/"+volumes[i].Name]; ok {
+			volumes[i].Usage = &u
+		}
+	}
+}
+
+func (vc *VolumeController) getStatsSummary(ctx context.Context, node string) (*statsSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, statsSummaryTimeout)
+	defer cancel()
+
+	raw, err := vc.clientset.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		Name(node).
+		SubResource("proxy").
+		Suffix("stats", "summary").
+		DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats summary of node %s: %v", node, err)
+	}
+
+	var summary statsSummary
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		return nil, fmt.Errorf("failed to decode stats summary of node %s: %v", node, err)
+	}
+	return &summary, nil
+}
+
+// mergeVolumeUsage adds the PVC-backed volumes of a summary to usage. Only
+// volumes carrying a pvcRef are PVCs; emptyDir, configMap and other volume
+// types are ignored. A PVC mounted by several pods is reported once
+func mergeVolumeUsage(usage map[string]VolumeUsage, node string, summary *statsSummary) {
+	value := func(v *uint64) int64 {
+		if v == nil {
+			return 0
+		}
+		return int64(*v)
+	}
+
+	for _, pod := range summary.Pods {
+		for _, volume := range pod.Volumes {
+			if volume.PVCRef == nil {
+				continue
+			}
+			key := volume.PVCRef.Namespace + "/" + volume.PVCRef.Name
+			if _, ok := usage[key]; ok {
+				continue
+			}
+			usage[key] = VolumeUsage{
+				UsedBytes:      value(volume.UsedBytes),
+				AvailableBytes: value(volume.AvailableBytes),
+				CapacityBytes:  value(volume.CapacityBytes),
+				Inodes:         value(volume.Inodes),
+				InodesUsed:     value(volume.InodesUsed),
+				InodesFree:     value(volume.InodesFree),
+				Node:           node,
+			}
+		}
+	}
+}
+
+func isNodeReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,614 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// originalReplicasAnnotation records the replica count a workload had
+	// before it was scaled down, so an interrupted run can be resumed
+	originalReplicasAnnotation = "kubegreen.io/original-replicas"
+	// originalSuspendAnnotation records whether a CronJob was suspended
+	// before it was paused
+	originalSuspendAnnotation = "kubegreen.io/original-suspend"
+	// quiescedForAnnotation records the PVC a workload was paused for
+	quiescedForAnnotation = "kubegreen.io/quiesced-for"
+	// quiescedNodeSelector is added to a DaemonSet's node selector so that
+	// no node matches and all of its pods are removed
+	quiescedNodeSelector = "kubegreen.io/quiesced"
+)
+
+// Workload kinds that can own pods mounting a PVC
+const (
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindReplicaSet  = "ReplicaSet"
+	KindDaemonSet   = "DaemonSet"
+	KindJob         = "Job"
+	KindCronJob     = "CronJob"
+)
+
+// Workload is a controller whose pods mount a PVC
+type Workload struct {
+	Kind string
+	Name string
+	// Replicas is the replica count the workload is restored to. Only set
+	// for Deployments, StatefulSets and ReplicaSets
+	Replicas int32
+}
+
+func (w Workload) String() string {
+	return fmt.Sprintf("%s %s", w.Kind, w.Name)
+}
+
+// Supported reports whether kubegreen knows how to pause the workload
+func (w Workload) Supported() bool {
+	switch w.Kind {
+	case KindDeployment, KindStatefulSet, KindReplicaSet, KindDaemonSet, KindJob, KindCronJob:
+		return true
+	}
+	return false
+}
+
+// Restorable reports whether the workload can be brought back after it was
+// paused. Jobs are deleted and cannot be restored
+func (w Workload) Restorable() bool {
+	return w.Supported() && w.Kind != KindJob
+}
+
+func (w Workload) key() string {
+	return w.Kind + "/" + w.Name
+}
+
+func podSpecUsesPVC(spec corev1.PodSpec, pvcName string) bool {
+	for _, volume := range spec.Volumes {
+		if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == pvcName {
+			return true
+		}
+	}
+	return false
+}
+
+// statefulSetOwnsClaim reports whether pvcName was generated from one of the
+// StatefulSet's volumeClaimTemplates (<template>-<statefulset>-<ordinal>)
+func statefulSetOwnsClaim(sts *appsv1.StatefulSet, pvcName string) bool {
+	for _, template := range sts.Spec.VolumeClaimTemplates {
+		prefix := fmt.Sprintf("%s-%s-", template.Name, sts.Name)
+		if !strings.HasPrefix(pvcName, prefix) {
+			continue
+		}
+		if _, err := strconv.Atoi(strings.TrimPrefix(pvcName, prefix)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// findPVCConsumers returns the workloads that mount a PVC, either through
+// their pod template or through running pods whose owner references lead
+// to them
+func (vc *VolumeController) findPVCConsumers(ctx context.Context, namespace, pvcName string) ([]Workload, error) {
+	found := make(map[string]Workload)
+	add := func(w Workload) {
+		if _, ok := found[w.key()]; !ok {
+			found[w.key()] = w
+		}
+	}
+
+	deployments, err := vc.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %v", err)
+	}
+	for _, d := range deployments.Items {
+		if podSpecUsesPVC(d.Spec.Template.Spec, pvcName) {
+			add(Workload{Kind: KindDeployment, Name: d.Name, Replicas: recordedReplicas(d.ObjectMeta, d.Spec.Replicas)})
+		}
+	}
+
+	statefulSets, err := vc.clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %v", err)
+	}
+	for _, sts := range statefulSets.Items {
+		if podSpecUsesPVC(sts.Spec.Template.Spec, pvcName) || statefulSetOwnsClaim(&sts, pvcName) {
+			add(Workload{Kind: KindStatefulSet, Name: sts.Name, Replicas: recordedReplicas(sts.ObjectMeta, sts.Spec.Replicas)})
+		}
+	}
+
+	replicaSets, err := vc.clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replicasets: %v", err)
+	}
+	for _, rs := range replicaSets.Items {
+		// ReplicaSets managed by a Deployment are paused through it
+		if metav1.GetControllerOf(&rs) == nil && podSpecUsesPVC(rs.Spec.Template.Spec, pvcName) {
+			add(Workload{Kind: KindReplicaSet, Name: rs.Name, Replicas: recordedReplicas(rs.ObjectMeta, rs.Spec.Replicas)})
+		}
+	}
+
+	daemonSets, err := vc.clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %v", err)
+	}
+	for _, ds := range daemonSets.Items {
+		if podSpecUsesPVC(ds.Spec.Template.Spec, pvcName) {
+			add(Workload{Kind: KindDaemonSet, Name: ds.Name})
+		}
+	}
+
+	cronJobs, err := vc.clientset.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cronjobs: %v", err)
+	}
+	for _, cj := range cronJobs.Items {
+		if podSpecUsesPVC(cj.Spec.JobTemplate.Spec.Template.Spec, pvcName) {
+			add(Workload{Kind: KindCronJob, Name: cj.Name})
+		}
+	}
+
+	jobs, err := vc.clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %v", err)
+	}
+	for _, job := range jobs.Items {
+		// Jobs created by a CronJob are paused through it
+		if metav1.GetControllerOf(&job) == nil && job.Status.CompletionTime == nil &&
+			podSpecUsesPVC(job.Spec.Template.Spec, pvcName) {
+			add(Workload{Kind: KindJob, Name: job.Name})
+		}
+	}
+
+	// Running pods may still come from an older template revision, so walk
+	// their owner references as well
+	pods, err := vc.findPodsUsingPVC(namespace, pvcName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for pods using PVC: %v", err)
+	}
+	for _, pod := range pods {
+		workload, err := vc.resolvePodController(ctx, &pod)
+		if err != nil {
+			return nil, err
+		}
+		if workload == nil {
+			continue
+		}
+		if _, ok := found[workload.key()]; ok {
+			continue
+		}
+		if err := vc.fillWorkloadReplicas(ctx, namespace, workload); err != nil {
+			return nil, err
+		}
+		add(*workload)
+	}
+
+	workloads := make([]Workload, 0, len(found))
+	for _, w := range found {
+		workloads = append(workloads, w)
+	}
+	sort.Slice(workloads, func(i, j int) bool {
+		return workloads[i].key() < workloads[j].key()
+	})
+	return workloads, nil
+}
+
+// resolvePodController walks the owner references of a pod up to the
+// workload controlling it. It returns nil for bare pods
+func (vc *VolumeController) resolvePodController(ctx context.Context, pod *corev1.Pod) (*Workload, error) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return nil, nil
+	}
+
+	switch ref.Kind {
+	case KindReplicaSet:
+		rs, err := vc.clientset.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get replicaset %s: %v", ref.Name, err)
+		}
+		if owner := metav1.GetControllerOf(rs); owner != nil && owner.Kind == KindDeployment {
+			return &Workload{Kind: KindDeployment, Name: owner.Name}, nil
+		}
+		return &Workload{Kind: KindReplicaSet, Name: rs.Name}, nil
+	case KindJob:
+		job, err := vc.clientset.BatchV1().Jobs(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get job %s: %v", ref.Name, err)
+		}
+		if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == KindCronJob {
+			return &Workload{Kind: KindCronJob, Name: owner.Name}, nil
+		}
+		return &Workload{Kind: KindJob, Name: job.Name}, nil
+	}
+	return &Workload{Kind: ref.Kind, Name: ref.Name}, nil
+}
+
+func (vc *VolumeController) fillWorkloadReplicas(ctx context.Context, namespace string, workload *Workload) error {
+	switch workload.Kind {
+	case KindDeployment:
+		d, err := vc.clientset.AppsV1().Deployments(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get deployment %s: %v", workload.Name, err)
+		}
+		workload.Replicas = recordedReplicas(d.ObjectMeta, d.Spec.Replicas)
+	case KindStatefulSet:
+		sts, err := vc.clientset.AppsV1().StatefulSets(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get statefulset %s: %v", workload.Name, err)
+		}
+		workload.Replicas = recordedReplicas(sts.ObjectMeta, sts.Spec.Replicas)
+	case KindReplicaSet:
+		rs, err := vc.clientset.AppsV1().ReplicaSets(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get replicaset %s: %v", workload.Name, err)
+		}
+		workload.Replicas = recordedReplicas(rs.ObjectMeta, rs.Spec.Replicas)
+	}
+	return nil
+}
+
+// recordedReplicas returns the replica count recorded by an earlier,
+// interrupted run if there is one, or the current replica count otherwise
+func recordedReplicas(meta metav1.ObjectMeta, replicas *int32) int32 {
+	if recorded, ok := meta.Annotations[originalReplicasAnnotation]; ok {
+		if n, err := strconv.ParseInt(recorded, 10, 32); err == nil {
+			return int32(n)
+		}
+	}
+	return replicasOrDefault(replicas)
+}
+
+// markQuiesced records the original value under key, unless an earlier run
+// already recorded it
+func markQuiesced(meta *metav1.ObjectMeta, pvcName, key, value string) {
+	if meta.Annotations == nil {
+		meta.Annotations = make(map[string]string)
+	}
+	if _, ok := meta.Annotations[key]; !ok {
+		meta.Annotations[key] = value
+	}
+	meta.Annotations[quiescedForAnnotation] = pvcName
+}
+
+func clearQuiesced(meta *metav1.ObjectMeta) {
+	delete(meta.Annotations, originalReplicasAnnotation)
+	delete(meta.Annotations, originalSuspendAnnotation)
+	delete(meta.Annotations, quiescedForAnnotation)
+}
+
+// quiesceWorkload stops a workload from running pods: scalable workloads
+// are scaled to zero, CronJobs are suspended, Jobs are deleted and
+// DaemonSets get a node selector no node matches. The active Jobs of a
+// CronJob are deleted afterwards by deleteActiveJobs, so that a suspended
+// CronJob is restored even if they cannot be deleted
+func (vc *VolumeController) quiesceWorkload(ctx context.Context, namespace string, workload Workload, pvcName string, progress ProgressFunc) error {
+	var zero int32 = 0
+	replicas := strconv.Itoa(int(workload.Replicas))
+	apps := vc.clientset.AppsV1()
+	batch := vc.clientset.BatchV1()
+
+	var err error
+	switch workload.Kind {
+	case KindDeployment:
+		progress.step("Scaling down deployment %s (replicas: %d)", workload.Name, workload.Replicas)
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			d, err := apps.Deployments(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			markQuiesced(&d.ObjectMeta, pvcName, originalReplicasAnnotation, replicas)
+			d.Spec.Replicas = &zero
+			_, err = apps.Deployments(namespace).Update(ctx, d, metav1.UpdateOptions{})
+			return err
+		})
+	case KindStatefulSet:
+		progress.step("Scaling down statefulset %s (replicas: %d)", workload.Name, workload.Replicas)
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			sts, err := apps.StatefulSets(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			markQuiesced(&sts.ObjectMeta, pvcName, originalReplicasAnnotation, replicas)
+			sts.Spec.Replicas = &zero
+			_, err = apps.StatefulSets(namespace).Update(ctx, sts, metav1.UpdateOptions{})
+			return err
+		})
+	case KindReplicaSet:
+		progress.step("Scaling down replicaset %s (replicas: %d)", workload.Name, workload.Replicas)
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			rs, err := apps.ReplicaSets(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			markQuiesced(&rs.ObjectMeta, pvcName, originalReplicasAnnotation, replicas)
+			rs.Spec.Replicas = &zero
+			_, err = apps.ReplicaSets(namespace).Update(ctx, rs, metav1.UpdateOptions{})
+			return err
+		})
+	case KindDaemonSet:
+		progress.step("Unscheduling daemonset %s from all nodes", workload.Name)
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			ds, err := apps.DaemonSets(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if ds.Annotations == nil {
+				ds.Annotations = make(map[string]string)
+			}
+			ds.Annotations[quiescedForAnnotation] = pvcName
+			if ds.Spec.Template.Spec.NodeSelector == nil {
+				ds.Spec.Template.Spec.NodeSelector = make(map[string]string)
+			}
+			ds.Spec.Template.Spec.NodeSelector[quiescedNodeSelector] = "true"
+			_, err = apps.DaemonSets(namespace).Update(ctx, ds, metav1.UpdateOptions{})
+			return err
+		})
+	case KindCronJob:
+		progress.step("Suspending cronjob %s", workload.Name)
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			cj, err := batch.CronJobs(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			suspended := cj.Spec.Suspend != nil && *cj.Spec.Suspend
+			markQuiesced(&cj.ObjectMeta, pvcName, originalSuspendAnnotation, strconv.FormatBool(suspended))
+			suspend := true
+			cj.Spec.Suspend = &suspend
+			_, err = batch.CronJobs(namespace).Update(ctx, cj, metav1.UpdateOptions{})
+			return err
+		})
+	case KindJob:
+		progress.step("Deleting job %s (it will not be restored)", workload.Name)
+		err = vc.deleteJob(ctx, namespace, workload.Name)
+	default:
+		return fmt.Errorf("cannot pause %s: unsupported workload kind", workload)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to pause %s: %v", workload, err)
+	}
+	return nil
+}
+
+// deleteActiveJobs deletes the Jobs still running for a suspended CronJob.
+// Other workloads have none
+func (vc *VolumeController) deleteActiveJobs(ctx context.Context, namespace string, workload Workload, progress ProgressFunc) error {
+	if workload.Kind != KindCronJob {
+		return nil
+	}
+	cj, err := vc.clientset.BatchV1().CronJobs(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %v", workload, err)
+	}
+	for _, ref := range cj.Status.Active {
+		progress.detail("Deleting active job %s", ref.Name)
+		if err := vc.deleteJob(ctx, namespace, ref.Name); err != nil {
+			return fmt.Errorf("failed to delete active job %s of %s: %v", ref.Name, workload, err)
+		}
+	}
+	return nil
+}
+
+func (vc *VolumeController) deleteJob(ctx context.Context, namespace, name string) error {
+	propagation := metav1.DeletePropagationBackground
+	err := vc.clientset.BatchV1().Jobs(namespace).Delete(ctx, name, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (vc *VolumeController) restoreWorkloads(ctx context.Context, namespace string, workloads []Workload, progress ProgressFunc) error {
+	var errs []string
+	for _, workload := range workloads {
+		if !workload.Restorable() {
+			continue
+		}
+		if err := vc.restoreWorkload(ctx, namespace, workload); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		progress.detail("Restored %s", workload)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// restoreWorkload undoes quiesceWorkload using the original values recorded
+// in the workload's annotations
+func (vc *VolumeController) restoreWorkload(ctx context.Context, namespace string, workload Workload) error {
+	apps := vc.clientset.AppsV1()
+	batch := vc.clientset.BatchV1()
+
+	var err error
+	switch workload.Kind {
+	case KindDeployment:
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			d, err := apps.Deployments(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			replicas := recordedReplicas(d.ObjectMeta, d.Spec.Replicas)
+			d.Spec.Replicas = &replicas
+			clearQuiesced(&d.ObjectMeta)
+			_, err = apps.Deployments(namespace).Update(ctx, d, metav1.UpdateOptions{})
+			return err
+		})
+	case KindStatefulSet:
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			sts, err := apps.StatefulSets(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			replicas := recordedReplicas(sts.ObjectMeta, sts.Spec.Replicas)
+			sts.Spec.Replicas = &replicas
+			clearQuiesced(&sts.ObjectMeta)
+			_, err = apps.StatefulSets(namespace).Update(ctx, sts, metav1.UpdateOptions{})
+			return err
+		})
+	case KindReplicaSet:
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			rs, err := apps.ReplicaSets(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			replicas := recordedReplicas(rs.ObjectMeta, rs.Spec.Replicas)
+			rs.Spec.Replicas = &replicas
+			clearQuiesced(&rs.ObjectMeta)
+			_, err = apps.ReplicaSets(namespace).Update(ctx, rs, metav1.UpdateOptions{})
+			return err
+		})
+	case KindDaemonSet:
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			ds, err := apps.DaemonSets(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			delete(ds.Spec.Template.Spec.NodeSelector, quiescedNodeSelector)
+			clearQuiesced(&ds.ObjectMeta)
+			_, err = apps.DaemonSets(namespace).Update(ctx, ds, metav1.UpdateOptions{})
+			return err
+		})
+	case KindCronJob:
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			cj, err := batch.CronJobs(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			suspend, _ := strconv.ParseBool(cj.Annotations[originalSuspendAnnotation])
+			cj.Spec.Suspend = &suspend
+			clearQuiesced(&cj.ObjectMeta)
+			_, err = batch.CronJobs(namespace).Update(ctx, cj, metav1.UpdateOptions{})
+			return err
+		})
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to restore %s: %v", workload, err)
+	}
+	return nil
+}
+
+// PausedWorkload is a workload left paused by an interrupted or SkipRestore
+// DeleteVolume run
+type PausedWorkload struct {
+	Namespace string
+	// PVCName is the PVC the workload was paused for
+	PVCName  string
+	Workload Workload
+}
+
+// ListPausedWorkloads returns the workloads left paused by kubegreen. An
+// empty namespace or pvcName matches all of them
+func (vc *VolumeController) ListPausedWorkloads(namespace, pvcName string) ([]PausedWorkload, error) {
+	paused, err := vc.listQuiescedWorkloads(context.TODO(), namespace)
+	if err != nil {
+		return nil, err
+	}
+	var matched []PausedWorkload
+	for _, p := range paused {
+		if pvcName == "" || p.PVCName == pvcName {
+			matched = append(matched, p)
+		}
+	}
+	return matched, nil
+}
+
+// RestoreWorkloads restores paused workloads listed by ListPausedWorkloads,
+// using the original values recorded in their annotations. It returns the
+// number of restored workloads
+func (vc *VolumeController) RestoreWorkloads(ctx context.Context, paused []PausedWorkload, progress ProgressFunc) (int, error) {
+	progress.step("Restoring %d paused workload(s)", len(paused))
+	restored := 0
+	for _, p := range paused {
+		if err := vc.restoreWorkload(ctx, p.Namespace, p.Workload); err != nil {
+			return restored, err
+		}
+		progress.detail("Restored %s in %s", p.Workload, p.Namespace)
+		restored++
+	}
+	progress.success("Restored %d workload(s)", restored)
+	return restored, nil
+}
+
+func (vc *VolumeController) listQuiescedWorkloads(ctx context.Context, namespace string) ([]PausedWorkload, error) {
+	var paused []PausedWorkload
+	add := func(kind string, meta metav1.ObjectMeta) {
+		if pvcName, ok := meta.Annotations[quiescedForAnnotation]; ok {
+			paused = append(paused, PausedWorkload{
+				Namespace: meta.Namespace,
+				PVCName:   pvcName,
+				Workload:  Workload{Kind: kind, Name: meta.Name},
+			})
+		}
+	}
+
+	deployments, err := vc.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %v", err)
+	}
+	for _, d := range deployments.Items {
+		add(KindDeployment, d.ObjectMeta)
+	}
+
+	statefulSets, err := vc.clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %v", err)
+	}
+	for _, sts := range statefulSets.Items {
+		add(KindStatefulSet, sts.ObjectMeta)
+	}
+
+	replicaSets, err := vc.clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replicasets: %v", err)
+	}
+	for _, rs := range replicaSets.Items {
+		add(KindReplicaSet, rs.ObjectMeta)
+	}
+
+	daemonSets, err := vc.clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %v", err)
+	}
+	for _, ds := range daemonSets.Items {
+		add(KindDaemonSet, ds.ObjectMeta)
+	}
+
+	cronJobs, err := vc.clientset.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cronjobs: %v", err)
+	}
+	for _, cj := range cronJobs.Items {
+		add(KindCronJob, cj.ObjectMeta)
+	}
+
+	return paused, nil
+}
+
+// isBarePod reports whether a pod has no controller that would recreate it
+func isBarePod(pod *corev1.Pod) bool {
+	return metav1.GetControllerOf(pod) == nil
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"kubegreen/internal/controller"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (m *Model) handleCertExportInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.Message = ""
+		m.State = ListSubMenu
+	case tea.KeyEnter:
+		m.State = ListSubMenu
+		path, err := m.exportCertificates(m.certExport)
+		if err != nil {
+			m.Message = fmt.Sprintf("Failed to export certificates:\n%v", err)
+			return m, nil
+		}
+		m.Message = fmt.Sprintf("Exported %d certificates to %s", len(m.certificates), path)
+	case tea.KeySpace:
+		m.certExport += " "
+	default:
+		m.certExport = editText(m.certExport, msg)
+	}
+	return m, nil
+}
+
+// exportCertificates writes the listed certificates from a "format [file]"
+// input. Without a file, the report is written to the current directory
+// under a dated name
+func (m *Model) exportCertificates(input string) (string, error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", fmt.Errorf("enter a format (json, csv or markdown) and optionally a file")
+	}
+	format, err := controller.ParseExportFormat(fields[0])
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	path := fmt.Sprintf("certificates-%s.%s", now.Format("2006-01-02"), format.Extension())
+	if len(fields) == 2 {
+		path = fields[1]
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %v", path, err)
+	}
+	if err := controller.ExportCertificates(file, m.certificates, format, now); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return path, nil
+}
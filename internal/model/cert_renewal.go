@@ -0,0 +1,195 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"kubegreen/internal/controller"
+
+	tea "github.com/charmbracelet/bubbletea"
+	certificatesv1 "k8s.io/api/certificates/v1"
+)
+
+// renewalFields are the labels of the renewal form, in the order of
+// Model.renewalForm
+var renewalFields = []string{
+	"Common name",
+	"Organizations",
+	"DNS names",
+	"IP addresses",
+	"Key algorithm",
+	"Key size",
+	"Signer",
+	"Usages",
+	"Validity",
+}
+
+const (
+	renewalCommonName = iota
+	renewalOrganizations
+	renewalDNSNames
+	renewalIPAddresses
+	renewalKeyAlgorithm
+	renewalKeySize
+	renewalSigner
+	renewalUsages
+	renewalValidity
+)
+
+// openRenewalForm fills the renewal form from the current certificate
+func (m *Model) openRenewalForm() {
+	req, err := m.certCtl.RenewalDefaults(m.selectedCert.Namespace, m.selectedCert.Name)
+	if err != nil {
+		m.Message = fmt.Sprintf("Failed to read certificate:\n%v", err)
+		return
+	}
+
+	usages := make([]string, len(req.Usages))
+	for i, usage := range req.Usages {
+		usages[i] = string(usage)
+	}
+	m.renewalSubject = req.Subject
+	m.renewalForm = make([]string, len(renewalFields))
+	m.renewalForm[renewalCommonName] = req.CommonName
+	m.renewalForm[renewalOrganizations] = strings.Join(req.Organizations, ", ")
+	m.renewalForm[renewalDNSNames] = strings.Join(req.DNSNames, ", ")
+	m.renewalForm[renewalIPAddresses] = strings.Join(req.IPAddresses, ", ")
+	m.renewalForm[renewalKeyAlgorithm] = req.KeyAlgorithm
+	m.renewalForm[renewalKeySize] = strconv.Itoa(req.KeySize)
+	m.renewalForm[renewalSigner] = req.SignerName
+	m.renewalForm[renewalUsages] = strings.Join(usages, ", ")
+	m.renewalForm[renewalValidity] = formatValidity(req.Duration)
+
+	m.State = RenewalForm
+	m.Cursor = 0
+	m.Message = "Review the certificate to request:"
+}
+
+// renewalRequest parses the renewal form
+func (m *Model) renewalRequest() (*controller.RenewalRequest, error) {
+	form := m.renewalForm
+	req := &controller.RenewalRequest{
+		Subject:       m.renewalSubject,
+		CommonName:    strings.TrimSpace(form[renewalCommonName]),
+		Organizations: splitList(form[renewalOrganizations]),
+		DNSNames:      splitList(form[renewalDNSNames]),
+		IPAddresses:   splitList(form[renewalIPAddresses]),
+		KeyAlgorithm:  strings.TrimSpace(form[renewalKeyAlgorithm]),
+		SignerName:    strings.TrimSpace(form[renewalSigner]),
+	}
+	for _, usage := range splitList(form[renewalUsages]) {
+		req.Usages = append(req.Usages, certificatesv1.KeyUsage(usage))
+	}
+	if req.KeyAlgorithm != "Ed25519" {
+		size, err := strconv.Atoi(strings.TrimSpace(form[renewalKeySize]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid key size %q", form[renewalKeySize])
+		}
+		req.KeySize = size
+	}
+	duration, err := parseValidity(form[renewalValidity])
+	if err != nil {
+		return nil, err
+	}
+	req.Duration = duration
+
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (m *Model) handleRenewalForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.Message = "Certificate renewal cancelled"
+		m.State = CertDetailView
+		m.Cursor = 0
+	case tea.KeyUp, tea.KeyShiftTab:
+		if m.Cursor > 0 {
+			m.Cursor--
+		}
+	case tea.KeyDown, tea.KeyTab:
+		if m.Cursor < len(renewalFields)-1 {
+			m.Cursor++
+		}
+	case tea.KeyEnter:
+		req, err := m.renewalRequest()
+		if err != nil {
+			m.Message = fmt.Sprintf("Invalid renewal request: %v", err)
+			return m, nil
+		}
+		m.renewal = req
+		m.State = RenewalConfirm
+		approval := "an approver must approve the CSR"
+		if m.config.AutoApproveCSRs {
+			approval = "the CSR is auto-approved"
+		}
+		m.Message = fmt.Sprintf("Do you want to renew certificate %s/%s with %s (%s)? (y/n)",
+			m.selectedCert.Namespace,
+			m.selectedCert.Name,
+			req.SignerName,
+			approval)
+	case tea.KeySpace:
+		m.renewalForm[m.Cursor] += " "
+	default:
+		m.renewalForm[m.Cursor] = editText(m.renewalForm[m.Cursor], msg)
+	}
+	return m, nil
+}
+
+func (m *Model) renderRenewalForm() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Renew certificate %s/%s\n\n", m.selectedCert.Namespace, m.selectedCert.Name))
+	for i, label := range renewalFields {
+		cursor := " "
+		value := m.renewalForm[i]
+		if m.Cursor == i && m.State == RenewalForm {
+			cursor = ">"
+			value += "_"
+		}
+		b.WriteString(fmt.Sprintf("%s %-14s %s\n", cursor, label+":", value))
+	}
+	b.WriteString("\nLists are comma separated. Key algorithm is RSA, ECDSA or Ed25519.\n")
+	b.WriteString("Validity is a number of days (365d) or a duration (12h).\n")
+	b.WriteString("The other subject attributes (OU, C, ST, L...) of the current certificate are kept.\n")
+	return b.String()
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func formatValidity(d time.Duration) string {
+	day := 24 * time.Hour
+	if d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
+
+func parseValidity(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid validity %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid validity %q", value)
+	}
+	return d, nil
+}
@@ -0,0 +1,514 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"kubegreen/internal/controller"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func (m *Model) isCertificateState() bool {
+	switch m.State {
+	case CertDetailView, RenewalForm, RenewalConfirm, CertCAInput, CertManagerRenewConfirm, LocalCAInput, CertExportInput:
+		return true
+	}
+	return false
+}
+
+func (m *Model) handleCertificates() string {
+	certs, err := m.certCtl.GetCertificates()
+	if err != nil && len(certs) == 0 {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if len(certs) == 0 {
+		return "No certificates found"
+	}
+
+	m.certificates = certs
+	m.buildCertificateRows()
+
+	m.State = ListSubMenu
+	m.Cursor = 0
+	if err != nil {
+		// Some sources could not be read, list the others
+		return fmt.Sprintf("Select certificate to view details\nWarning: %v", err)
+	}
+	return "Select certificate to view details"
+}
+
+// buildCertificateRows sorts the certificates, soonest expiry first unless
+// sorted by name, and rebuilds the rows of the list
+func (m *Model) buildCertificateRows() {
+	sort.SliceStable(m.certificates, func(i, j int) bool {
+		a, b := m.certificates[i], m.certificates[j]
+		if m.certSortByName {
+			if a.Namespace != b.Namespace {
+				return a.Namespace < b.Namespace
+			}
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			if a.Kind != b.Kind {
+				return a.Kind < b.Kind
+			}
+			return a.FieldPath < b.FieldPath
+		}
+		return a.NotAfter.Before(b.NotAfter)
+	})
+
+	m.SubChoices = make([]string, len(m.certificates)+1) // +1 for the header
+
+	// Create the header
+	m.SubChoices[0] = "NAMESPACE\tKIND\tNAME\tFIELD\tEXPIRES IN\tSTATUS\tCHECKS"
+
+	for i, cert := range m.certificates {
+		m.SubChoices[i+1] = fmt.Sprintf("%s\t%s\t%s\t%s\t%d days\t%s\t%s",
+			orDash(cert.Namespace),
+			cert.Kind,
+			cert.Name,
+			cert.FieldPath,
+			cert.DaysRemaining,
+			cert.Severity,
+			cert.Validation.Status())
+	}
+}
+
+// toggleCertificateSort switches the list between expiry and name order
+func (m *Model) toggleCertificateSort() {
+	m.certSortByName = !m.certSortByName
+	m.buildCertificateRows()
+	m.Cursor = 0
+	if m.certSortByName {
+		m.Message = "Sorted by namespace and name"
+	} else {
+		m.Message = "Sorted by soonest expiry"
+	}
+}
+
+func (m *Model) renderCertificateList() string {
+	var b strings.Builder
+
+	counts := make(map[controller.Severity]int)
+	for _, cert := range m.certificates {
+		counts[cert.Severity]++
+	}
+	var summary []string
+	for i := len(controller.Severities) - 1; i >= 0; i-- {
+		severity := controller.Severities[i]
+		summary = append(summary, severityStyle(severity).Render(fmt.Sprintf("%d %s", counts[severity], severity)))
+	}
+	b.WriteString(strings.Join(summary, "  ") + "\n\n")
+
+	header := " "
+	if m.Cursor == 0 {
+		header = ">"
+	}
+	b.WriteString(fmt.Sprintf("%s %-20s %-30s %-40s %-45s %12s  %-9s %s\n", header,
+		"NAMESPACE", "KIND", "NAME", "FIELD", "EXPIRES IN", "STATUS", "CHECKS"))
+	for i, cert := range m.certificates {
+		cursor := " "
+		if m.Cursor == i+1 { // +1 for the header row
+			cursor = ">"
+		}
+		line := fmt.Sprintf("%s %-20s %-30s %-40s %-45s %7d days  %-9s %s", cursor, orDash(cert.Namespace), cert.Kind,
+			cert.Name, cert.FieldPath, cert.DaysRemaining, cert.Severity, cert.Validation.Status())
+		b.WriteString(severityStyle(cert.Severity).Render(line) + "\n")
+	}
+	return b.String()
+}
+
+func severityStyle(severity controller.Severity) lipgloss.Style {
+	switch severity {
+	case controller.SeverityWarning:
+		return warningStyle
+	case controller.SeverityCritical:
+		return criticalStyle
+	case controller.SeverityExpired:
+		return expiredStyle
+	}
+	return podNormalStyle
+}
+
+// handleCertificateDetails opens the detail screen of the certificate under
+// the cursor
+func (m *Model) handleCertificateDetails() {
+	if m.Cursor == 0 || len(m.certificates) == 0 { // Header row
+		return
+	}
+	m.lastCertCursor = m.Cursor
+	m.selectedCert = &m.certificates[m.Cursor-1] // -1 because first row is header
+	m.State = CertDetailView
+	m.Cursor = 0
+	m.Message = ""
+	m.loadCertManagerInfo()
+}
+
+// loadCertManagerInfo resolves the cert-manager resources of the selected
+// certificate
+func (m *Model) loadCertManagerInfo() {
+	m.certManager = nil
+	if m.selectedCert.CertManagerCertificate == "" {
+		return
+	}
+	info, err := m.certCtl.GetCertManagerInfo(m.selectedCert.Namespace, m.selectedCert.CertManagerCertificate)
+	if err != nil {
+		m.Message = fmt.Sprintf("Failed to read cert-manager resources:\n%v", err)
+		return
+	}
+	m.certManager = info
+}
+
+func (m *Model) backToCertificateList() {
+	m.State = ListSubMenu
+	m.Cursor = m.lastCertCursor
+}
+
+func (m *Model) handleCertificateMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.State == RenewalConfirm {
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "y", "Y":
+			return m, m.renewSelectedCertificate()
+		case "n", "N", "esc", "backspace":
+			m.Message = "Edit the request or press esc to cancel"
+			m.State = RenewalForm
+		}
+		return m, nil
+	}
+	if m.State == RenewalForm {
+		return m.handleRenewalForm(msg)
+	}
+	if m.State == CertManagerRenewConfirm {
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "y", "Y":
+			m.State = CertDetailView
+			name := m.selectedCert.CertManagerCertificate
+			if err := m.certCtl.TriggerCertManagerRenewal(m.selectedCert.Namespace, name); err != nil {
+				m.Message = fmt.Sprintf("Failed to renew certificate:\n%v", err)
+				return m, nil
+			}
+			m.loadCertManagerInfo()
+			m.Message = fmt.Sprintf("cert-manager is re-issuing Certificate %s, press u to refresh", name)
+		case "n", "N", "esc", "backspace":
+			m.Message = "Certificate renewal cancelled"
+			m.State = CertDetailView
+		}
+		return m, nil
+	}
+	if m.State == CertCAInput {
+		return m.handleCertCAInput(msg)
+	}
+	if m.State == LocalCAInput {
+		return m.handleLocalCAInput(msg)
+	}
+	if m.State == CertExportInput {
+		return m.handleCertExportInput(msg)
+	}
+
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.Cursor > 0 {
+			m.Cursor--
+		}
+	case "down", "j":
+		if m.Cursor < len(m.selectedCert.Chain)-1 {
+			m.Cursor++
+		}
+	case "r":
+		if !m.selectedCert.IsTLSSecret() {
+			m.Message = "Only the certificates of kubernetes.io/tls secrets can be renewed"
+			return m, nil
+		}
+		if m.certManager != nil {
+			// Writing the secret would fight cert-manager, let it re-issue
+			m.State = CertManagerRenewConfirm
+			m.Message = fmt.Sprintf("Ask cert-manager to re-issue Certificate %s/%s? (y/n)",
+				m.selectedCert.Namespace,
+				m.selectedCert.CertManagerCertificate)
+			return m, nil
+		}
+		m.openRenewalForm()
+	case "l":
+		if !m.selectedCert.IsTLSSecret() {
+			m.Message = "Only the certificates of kubernetes.io/tls secrets can be renewed"
+			return m, nil
+		}
+		if m.certManager != nil {
+			m.Message = "The certificate is managed by cert-manager, press r to renew it"
+			return m, nil
+		}
+		m.Message = "Re-issue with the CA keypair of secret namespace/name, or of files ca.crt ca.key:"
+		m.State = LocalCAInput
+	case "u":
+		m.Message = ""
+		m.reloadSelectedCertificate()
+		m.loadCertManagerInfo()
+		m.Cursor = 0
+	case "v":
+		if !m.selectedCert.IsTLSSecret() {
+			m.Message = "Only the certificates of kubernetes.io/tls secrets can be validated"
+			return m, nil
+		}
+		m.certCASecret = ""
+		m.Message = "Verify the chain against the ca.crt or tls.crt of secret (namespace/name):"
+		m.State = CertCAInput
+	case "esc", "backspace":
+		m.Message = ""
+		m.backToCertificateList()
+	}
+	return m, nil
+}
+
+func (m *Model) handleLocalCAInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.Message = "Certificate renewal cancelled"
+		m.State = CertDetailView
+	case tea.KeyEnter:
+		m.State = CertDetailView
+		ca, err := m.loadLocalCA(m.localCASource)
+		if err != nil {
+			m.Message = fmt.Sprintf("Failed to load CA:\n%v", err)
+			return m, nil
+		}
+		namespace, name := m.selectedCert.Namespace, m.selectedCert.Name
+		issued, err := m.certCtl.ReissueWithLocalCA(context.TODO(), namespace, name, ca)
+		if err != nil {
+			m.Message = fmt.Sprintf("Failed to re-issue certificate:\n%v", err)
+			return m, nil
+		}
+		m.reloadSelectedCertificate()
+		m.Cursor = 0
+		m.Message = fmt.Sprintf("Re-issued certificate %s/%s with CA %s, valid until %s",
+			namespace, name, ca.Cert.Subject, issued.NotAfter.Format("2006-01-02 15:04"))
+	case tea.KeySpace:
+		m.localCASource += " "
+	default:
+		m.localCASource = editText(m.localCASource, msg)
+	}
+	return m, nil
+}
+
+// loadLocalCA reads the CA keypair of a "namespace/name" secret or of a
+// "cert-file key-file" pair
+func (m *Model) loadLocalCA(source string) (*controller.LocalCA, error) {
+	fields := strings.Fields(source)
+	switch len(fields) {
+	case 1:
+		namespace, name, ok := strings.Cut(fields[0], "/")
+		if ok && namespace != "" && name != "" {
+			return m.certCtl.LoadLocalCAFromSecret(namespace, name)
+		}
+	case 2:
+		return controller.LoadLocalCAFromFiles(fields[0], fields[1])
+	}
+	return nil, fmt.Errorf("enter a secret as namespace/name or two files as ca.crt ca.key")
+}
+
+func (m *Model) handleCertCAInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.Message = ""
+		m.State = CertDetailView
+	case tea.KeyEnter:
+		m.State = CertDetailView
+		namespace, name, ok := strings.Cut(strings.TrimSpace(m.certCASecret), "/")
+		if !ok || namespace == "" || name == "" {
+			m.Message = "Enter the CA secret as namespace/name"
+			return m, nil
+		}
+		v, err := m.certCtl.ValidateCertificate(m.selectedCert.Namespace, m.selectedCert.Name, namespace, name)
+		if err != nil {
+			m.Message = fmt.Sprintf("Failed to validate certificate:\n%v", err)
+			return m, nil
+		}
+		m.selectedCert.Validation = *v
+		m.buildCertificateRows()
+		m.Message = fmt.Sprintf("Checked against %s: %s", v.CASource, v.Status())
+	default:
+		m.certCASecret = editText(m.certCASecret, msg)
+	}
+	return m, nil
+}
+
+func (m *Model) renewSelectedCertificate() tea.Cmd {
+	namespace, name, certCtl := m.selectedCert.Namespace, m.selectedCert.Name, m.certCtl
+	req, autoApprove := *m.renewal, m.config.AutoApproveCSRs
+
+	title := fmt.Sprintf("Renewing certificate %s/%s", namespace, name)
+	return m.startOperation(title, func(ctx context.Context, progress controller.ProgressFunc) error {
+		return certCtl.RenewCertificate(ctx, namespace, name, req, autoApprove, progress)
+	}, func(err error) {
+		m.State = CertDetailView
+		m.Cursor = 0
+		if err != nil {
+			m.Message = fmt.Sprintf("Failed to renew certificate:\n%v", err)
+			return
+		}
+		m.reloadSelectedCertificate()
+		m.Message = fmt.Sprintf("Successfully renewed certificate %s/%s", namespace, name)
+	})
+}
+
+// reloadSelectedCertificate refreshes the certificate list and the
+// certificate shown in the detail view
+func (m *Model) reloadSelectedCertificate() {
+	certs, _ := m.certCtl.GetCertificates()
+	selected := *m.selectedCert
+	for _, cert := range certs {
+		if sameCertificate(&cert, &selected) {
+			m.certificates = certs
+			m.buildCertificateRows()
+			break
+		}
+	}
+	for i := range m.certificates {
+		if sameCertificate(&m.certificates[i], &selected) {
+			m.selectedCert = &m.certificates[i]
+			m.lastCertCursor = i + 1 // +1 for the header
+			return
+		}
+	}
+}
+
+// sameCertificate reports whether two entries are the certificate at the
+// same field of the same object
+func sameCertificate(a, b *controller.CertInfo) bool {
+	return a.Kind == b.Kind && a.Namespace == b.Namespace && a.Name == b.Name && a.FieldPath == b.FieldPath
+}
+
+func (m *Model) renderCertificateDetails() string {
+	var b strings.Builder
+	cert := m.selectedCert
+
+	status := severityStyle(cert.Severity).Render(cert.Severity.String())
+	b.WriteString(fmt.Sprintf("Certificate %s: %s, %d days remaining\n\n", cert.Source(), status, cert.DaysRemaining))
+
+	b.WriteString("Chain:\n")
+	for i, c := range cert.Chain {
+		cursor := " "
+		if m.Cursor == i {
+			cursor = ">"
+		}
+		role := "intermediate"
+		switch {
+		case i == 0:
+			role = "leaf"
+		case c.Subject == c.Issuer:
+			role = "root"
+		}
+		b.WriteString(fmt.Sprintf("%s %d. %-12s %s\n", cursor, i+1, role, c.Subject))
+	}
+	if !cert.ChainOrdered {
+		b.WriteString(warningStyle.Render("  The chain is not ordered: a certificate is not issued by the one following it"))
+		b.WriteRune('\n')
+	}
+
+	if m.certManager != nil {
+		b.WriteString(renderCertManager(m.certManager))
+	} else if cert.CertManagerCertificate != "" {
+		b.WriteString(fmt.Sprintf("\ncert-manager Certificate %s not found, the secret is unmanaged\n", cert.CertManagerCertificate))
+	}
+
+	if cert.IsTLSSecret() {
+		v := cert.Validation
+		b.WriteString("\nChecks:\n")
+		b.WriteString(fmt.Sprintf("  Private key:         %s\n", checkResult(v.KeyError, "tls.key matches the leaf certificate")))
+		b.WriteString(fmt.Sprintf("  Chain:               %s\n", checkResult(v.ChainError, "verified against "+v.CASource)))
+	}
+	if m.State == CertCAInput {
+		b.WriteString(fmt.Sprintf("\nCA secret: %s_\n", m.certCASecret))
+	}
+	if m.State == LocalCAInput {
+		b.WriteString(fmt.Sprintf("\nCA: %s_\n", m.localCASource))
+	}
+
+	if len(cert.Chain) == 0 {
+		return b.String()
+	}
+
+	c := cert.Chain[m.Cursor]
+	ca := "no"
+	if c.IsCA {
+		ca = "yes"
+	}
+	b.WriteString(fmt.Sprintf("\nCertificate %d:\n", m.Cursor+1))
+	b.WriteString(fmt.Sprintf("  Subject:             %s\n", c.Subject))
+	b.WriteString(fmt.Sprintf("  Issuer:              %s\n", c.Issuer))
+	b.WriteString(fmt.Sprintf("  Serial Number:       %s\n", c.SerialNumber))
+	b.WriteString(fmt.Sprintf("  Valid From:          %s\n", c.NotBefore.Format("2006-01-02 15:04:05")))
+	b.WriteString(fmt.Sprintf("  Valid Until:         %s\n", c.NotAfter.Format("2006-01-02 15:04:05")))
+	b.WriteString(fmt.Sprintf("  SANs:                %s\n", orDash(strings.Join(c.SANs(), ", "))))
+	b.WriteString(fmt.Sprintf("  Key:                 %s %d bits\n", c.KeyAlgorithm, c.KeySize))
+	b.WriteString(fmt.Sprintf("  Signature Algorithm: %s\n", c.SignatureAlgorithm))
+	b.WriteString(fmt.Sprintf("  Key Usages:          %s\n", orDash(strings.Join(c.KeyUsages, ", "))))
+	b.WriteString(fmt.Sprintf("  Extended Usages:     %s\n", orDash(strings.Join(c.ExtKeyUsages, ", "))))
+	b.WriteString(fmt.Sprintf("  CA:                  %s\n", ca))
+	b.WriteString(fmt.Sprintf("  SHA-256 Fingerprint: %s\n", c.SHA256Fingerprint))
+	return b.String()
+}
+
+// checkResult renders the outcome of a validation check, failures in the
+// warning style
+func checkResult(failure, success string) string {
+	if failure != "" {
+		return warningStyle.Render(failure)
+	}
+	return success
+}
+
+func renderCertManager(info *controller.CertManagerInfo) string {
+	var b strings.Builder
+	b.WriteString("\ncert-manager:\n")
+	b.WriteString(renderCertManagerResource(&info.Certificate))
+	if !info.RenewalTime.IsZero() {
+		b.WriteString(fmt.Sprintf("    Revision %d, renewal scheduled %s\n", info.Revision, info.RenewalTime.Format("2006-01-02 15:04")))
+	}
+	b.WriteString(renderCertManagerResource(&info.Issuer))
+	if info.Request != nil {
+		b.WriteString(renderCertManagerResource(info.Request))
+	}
+	return b.String()
+}
+
+func renderCertManagerResource(r *controller.CertManagerResource) string {
+	var b strings.Builder
+	name := r.Name
+	if r.Namespace != "" {
+		name = r.Namespace + "/" + r.Name
+	}
+	state := "not ready"
+	switch {
+	case r.External:
+		state = "external issuer, conditions not read"
+	case r.Ready():
+		state = "ready"
+	}
+	line := fmt.Sprintf("  %s %s: %s", r.Kind, name, state)
+	if !r.External && !r.Ready() {
+		line = warningStyle.Render(line)
+	}
+	b.WriteString(line + "\n")
+	for _, cond := range r.Conditions {
+		b.WriteString(fmt.Sprintf("    %s=%s %s", cond.Type, cond.Status, cond.Reason))
+		if cond.Message != "" {
+			b.WriteString(": " + cond.Message)
+		}
+		b.WriteRune('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"kubegreen/internal/controller"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (m *Model) isCSRState() bool {
+	return m.State == CSRListView || m.State == CSRDecisionInput
+}
+
+func (m *Model) loadCSRs() {
+	csrs, err := m.certCtl.ListCSRs()
+	if err != nil {
+		m.Message = fmt.Sprintf("Error listing certificate signing requests: %v", err)
+		return
+	}
+	m.csrs = csrs
+	m.State = CSRListView
+	m.Cursor = 0
+}
+
+func (m *Model) handleCSRs(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.State == CSRDecisionInput {
+		return m.handleCSRDecisionInput(msg)
+	}
+
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.Cursor > 0 {
+			m.Cursor--
+		}
+	case "down", "j":
+		if m.Cursor < len(m.csrs)-1 {
+			m.Cursor++
+		}
+	case "r":
+		cursor := m.Cursor
+		m.Message = ""
+		m.loadCSRs()
+		m.Cursor = bound(cursor, 0, len(m.csrs)-1)
+	case "a", "d":
+		if len(m.csrs) == 0 {
+			return m, nil
+		}
+		m.csrApprove = msg.String() == "a"
+		m.csrReason = ""
+		verb := "Deny"
+		if m.csrApprove {
+			verb = "Approve"
+		}
+		m.Message = fmt.Sprintf("%s CSR %s. Reason:", verb, m.csrs[m.Cursor].Name)
+		m.State = CSRDecisionInput
+	case "esc", "backspace":
+		m.State = MainMenu
+		m.Cursor = m.lastMainCursor
+		m.Message = ""
+	}
+	return m, nil
+}
+
+func (m *Model) handleCSRDecisionInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	csr := m.csrs[m.Cursor]
+
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.Message = fmt.Sprintf("CSR %s left undecided", csr.Name)
+		m.State = CSRListView
+	case tea.KeyEnter:
+		reason := strings.TrimSpace(m.csrReason)
+		if reason == "" {
+			m.Message = "A reason is required"
+			return m, nil
+		}
+
+		decide, decided := m.certCtl.DenyCSR, "Denied"
+		if m.csrApprove {
+			decide, decided = m.certCtl.ApproveCSR, "Approved"
+		}
+		cursor := m.Cursor
+		if err := decide(csr.Name, reason); err != nil {
+			m.Message = fmt.Sprintf("Failed to decide CSR:\n%v", err)
+			m.State = CSRListView
+			return m, nil
+		}
+		m.loadCSRs()
+		m.Cursor = bound(cursor, 0, len(m.csrs)-1)
+		m.Message = fmt.Sprintf("%s CSR %s", decided, csr.Name)
+	case tea.KeySpace:
+		m.csrReason += " "
+	default:
+		m.csrReason = editText(m.csrReason, msg)
+	}
+	return m, nil
+}
+
+func (m *Model) renderCSRs() string {
+	var b strings.Builder
+	if len(m.csrs) == 0 {
+		b.WriteString("No certificate signing requests found\n")
+		return b.String()
+	}
+
+	b.WriteString(fmt.Sprintf("  %-40s %-30s %-40s %-35s %-8s %s\n", "NAME", "REQUESTER", "SIGNER", "USAGES", "AGE", "STATE"))
+	for i, csr := range m.csrs {
+		cursor := " "
+		if m.Cursor == i {
+			cursor = ">"
+		}
+		line := fmt.Sprintf("%s %-40s %-30s %-40s %-35s %-8s %s", cursor, csr.Name, csr.Requester,
+			csr.SignerName, strings.Join(csr.Usages, ","), csr.Age, csr.State)
+		if csr.State == controller.CSRPending {
+			line = warningStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	csr := m.csrs[m.Cursor]
+	b.WriteString(fmt.Sprintf("\nCSR %s:\n", csr.Name))
+	b.WriteString(fmt.Sprintf("  Requester: %s\n", orDash(csr.Requester)))
+	b.WriteString(fmt.Sprintf("  Signer:    %s\n", csr.SignerName))
+	b.WriteString(fmt.Sprintf("  Usages:    %s\n", orDash(strings.Join(csr.Usages, ", "))))
+	b.WriteString(fmt.Sprintf("  State:     %s\n", csr.State))
+	if csr.Message != "" {
+		b.WriteString(fmt.Sprintf("  Message:   %s\n", csr.Message))
+	}
+
+	if m.State == CSRDecisionInput {
+		b.WriteString(fmt.Sprintf("\nReason: %s_\n", m.csrReason))
+	}
+	return b.String()
+}
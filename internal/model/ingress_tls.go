@@ -0,0 +1,198 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"kubegreen/internal/controller"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (m *Model) isIngressTLSState() bool {
+	return m.State == IngressTLSView || m.State == IngressProbeInput
+}
+
+func (m *Model) loadIngressTLS() {
+	entries, err := m.certCtl.GetIngressTLS()
+	if err != nil {
+		m.Message = fmt.Sprintf("Error listing ingress TLS: %v", err)
+		return
+	}
+	m.ingressTLS = entries
+	m.ingressProbes = make(map[int][]controller.EndpointProbe)
+	m.State = IngressTLSView
+	m.Cursor = 0
+}
+
+func (m *Model) handleIngressTLS(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.State == IngressProbeInput {
+		return m.handleIngressProbeInput(msg)
+	}
+
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.Cursor > 0 {
+			m.Cursor--
+		}
+	case "down", "j":
+		if m.Cursor < len(m.ingressTLS)-1 {
+			m.Cursor++
+		}
+	case "r":
+		cursor := m.Cursor
+		m.Message = ""
+		m.loadIngressTLS()
+		m.Cursor = bound(cursor, 0, len(m.ingressTLS)-1)
+	case "p":
+		if len(m.ingressTLS) == 0 {
+			return m, nil
+		}
+		if len(m.ingressTLS[m.Cursor].Hosts) == 0 {
+			m.Message = "The tls entry has no hosts to probe"
+			return m, nil
+		}
+		m.Message = "Probe address as host[:port], empty to dial each host on port 443:"
+		m.State = IngressProbeInput
+	case "esc", "backspace":
+		m.State = MainMenu
+		m.Cursor = m.lastMainCursor
+		m.Message = ""
+	}
+	return m, nil
+}
+
+func (m *Model) handleIngressProbeInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.Message = ""
+		m.State = IngressTLSView
+	case tea.KeyEnter:
+		m.State = IngressTLSView
+		return m, m.probeSelectedIngress(strings.TrimSpace(m.ingressProbeAddress))
+	default:
+		m.ingressProbeAddress = editText(m.ingressProbeAddress, msg)
+	}
+	return m, nil
+}
+
+// probeSelectedIngress compares the certificate served for each host of the
+// selected entry with the one of its secret
+func (m *Model) probeSelectedIngress(address string) tea.Cmd {
+	cursor, entry := m.Cursor, m.ingressTLS[m.Cursor]
+	var probes []controller.EndpointProbe
+
+	title := fmt.Sprintf("Probing ingress %s/%s", entry.Namespace, entry.Ingress)
+	return m.startOperation(title, func(ctx context.Context, progress controller.ProgressFunc) error {
+		probes = controller.ProbeIngressTLS(ctx, &entry, address, progress)
+		return ctx.Err()
+	}, func(err error) {
+		m.ingressProbes[cursor] = probes
+		if err != nil {
+			m.Message = fmt.Sprintf("Probe interrupted: %v", err)
+			return
+		}
+		mismatched := 0
+		for _, probe := range probes {
+			if !probe.Matches {
+				mismatched++
+			}
+		}
+		if mismatched > 0 {
+			m.Message = fmt.Sprintf("%d of %d hosts do not serve the certificate of secret %s or could not be probed", mismatched, len(probes), entry.SecretName)
+		} else {
+			m.Message = fmt.Sprintf("Every host serves the certificate of secret %s", entry.SecretName)
+		}
+	})
+}
+
+func (m *Model) renderIngressTLS() string {
+	var b strings.Builder
+	if len(m.ingressTLS) == 0 {
+		b.WriteString("No ingress tls entries found\n")
+		return b.String()
+	}
+
+	b.WriteString(fmt.Sprintf("  %-25s %-30s %-30s %-40s %12s  %s\n", "NAMESPACE", "INGRESS", "SECRET", "HOSTS", "EXPIRES IN", "STATUS"))
+	for i, entry := range m.ingressTLS {
+		cursor := " "
+		if m.Cursor == i {
+			cursor = ">"
+		}
+		expires := "-"
+		if entry.Cert != nil {
+			expires = fmt.Sprintf("%d days", entry.Cert.DaysRemaining)
+		}
+		status := "OK"
+		switch {
+		case len(entry.Problems) > 0:
+			status = "Secret problem"
+		case len(entry.UncoveredHosts) > 0:
+			status = "Hosts not covered"
+		}
+		line := fmt.Sprintf("%s %-25s %-30s %-30s %-40s %12s  %s", cursor, entry.Namespace, entry.Ingress,
+			orDash(entry.SecretName), orDash(strings.Join(entry.Hosts, ",")), expires, status)
+		switch {
+		case !entry.OK():
+			line = warningStyle.Render(line)
+		case entry.Cert != nil:
+			line = severityStyle(entry.Cert.Severity).Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	entry := m.ingressTLS[m.Cursor]
+	b.WriteString(fmt.Sprintf("\nIngress %s/%s, secret %s:\n", entry.Namespace, entry.Ingress, orDash(entry.SecretName)))
+	if entry.Cert != nil && len(entry.Cert.Chain) > 0 {
+		leaf := entry.Cert.Chain[0]
+		b.WriteString(fmt.Sprintf("  Certificate: %s, valid until %s\n", leaf.Subject, leaf.NotAfter.Format("2006-01-02 15:04")))
+		b.WriteString(fmt.Sprintf("  SANs:        %s\n", orDash(strings.Join(leaf.SANs(), ", "))))
+	}
+	for _, problem := range entry.Problems {
+		b.WriteString(warningStyle.Render("  "+problem) + "\n")
+	}
+
+	uncovered := make(map[string]bool)
+	for _, host := range entry.UncoveredHosts {
+		uncovered[host] = true
+	}
+	probes := make(map[string]controller.EndpointProbe)
+	for _, probe := range m.ingressProbes[m.Cursor] {
+		probes[probe.Host] = probe
+	}
+	if len(entry.Hosts) > 0 {
+		b.WriteString("  Hosts:\n")
+	}
+	for _, host := range entry.Hosts {
+		line := fmt.Sprintf("    %-40s %s", host, "covered")
+		if uncovered[host] {
+			line = warningStyle.Render(fmt.Sprintf("    %-40s %s", host, "not covered by the SANs"))
+		}
+		b.WriteString(line)
+		if probe, ok := probes[host]; ok {
+			b.WriteString(", " + renderEndpointProbe(&probe))
+		}
+		b.WriteRune('\n')
+	}
+
+	if m.State == IngressProbeInput {
+		b.WriteString(fmt.Sprintf("\nProbe address: %s_\n", m.ingressProbeAddress))
+	}
+	return b.String()
+}
+
+func renderEndpointProbe(probe *controller.EndpointProbe) string {
+	switch {
+	case probe.Error != "":
+		return warningStyle.Render(fmt.Sprintf("probe of %s failed: %s", probe.Address, probe.Error))
+	case probe.Matches:
+		return fmt.Sprintf("%s serves the secret's certificate", probe.Address)
+	}
+	return warningStyle.Render(fmt.Sprintf("%s serves another certificate: %s (SHA-256 %s)",
+		probe.Address, probe.Served.Subject, probe.Served.SHA256Fingerprint))
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"kubegreen/internal/controller"
+)
+
+// loadKubeconfigAudit checks the certificates of every context, returning
+// a note on the contexts needing attention
+func (m *Model) loadKubeconfigAudit() string {
+	audits, err := m.certCtl.AuditKubeconfig()
+	if err != nil {
+		m.kubeconfigAudits = nil
+		return fmt.Sprintf("Certificate audit failed: %v", err)
+	}
+	m.kubeconfigAudits = make(map[string]*controller.KubeconfigCertAudit, len(audits))
+	attention := 0
+	for i := range audits {
+		m.kubeconfigAudits[audits[i].Context] = &audits[i]
+		if audits[i].Severity() != controller.SeverityOK {
+			attention++
+		}
+	}
+	if attention > 0 {
+		return fmt.Sprintf("%d context(s) have certificates expiring soon or expired", attention)
+	}
+	return ""
+}
+
+func (m *Model) renderContexts() string {
+	var b strings.Builder
+	b.WriteString("List sub-options:\n\n")
+
+	for i, name := range m.SubChoices {
+		cursor := " "
+		if m.Cursor == i {
+			cursor = ">"
+		}
+		line := fmt.Sprintf("%s %-40s", cursor, name)
+		audit := m.kubeconfigAudits[name]
+		if audit != nil {
+			line += fmt.Sprintf(" client cert %-18s CA %s",
+				kubeconfigCertSummary(audit.ClientCert), kubeconfigCertSummary(audit.CA))
+			line = severityStyle(audit.Severity()).Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	if m.Cursor >= len(m.SubChoices) {
+		return b.String()
+	}
+	audit := m.kubeconfigAudits[m.SubChoices[m.Cursor]]
+	if audit == nil {
+		return b.String()
+	}
+	b.WriteString(fmt.Sprintf("\nContext %s (user %s, cluster %s):\n", audit.Context, audit.User, audit.Cluster))
+	b.WriteString(renderKubeconfigCert("Client cert", audit.ClientCert, audit.ClientCertSource, audit.ClientCertNote))
+	b.WriteString(renderKubeconfigCert("Cluster CA", audit.CA, audit.CASource, audit.CANote))
+	return b.String()
+}
+
+func kubeconfigCertSummary(cert *controller.CertInfo) string {
+	if cert == nil {
+		return "-"
+	}
+	if cert.IsExpired {
+		return "expired"
+	}
+	return fmt.Sprintf("%d days", cert.DaysRemaining)
+}
+
+func renderKubeconfigCert(label string, cert *controller.CertInfo, source, note string) string {
+	if cert == nil {
+		return fmt.Sprintf("  %-12s %s\n", label+":", orDash(note))
+	}
+	subject := ""
+	if len(cert.Chain) > 0 {
+		subject = cert.Chain[0].Subject
+	}
+	status := severityStyle(cert.Severity).Render(cert.Severity.String())
+	return fmt.Sprintf("  %-12s %s, %s, expires %s (%d days) from %s\n", label+":", subject, status,
+		cert.NotAfter.Format("2006-01-02"), cert.DaysRemaining, source)
+}
@@ -0,0 +1,207 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"kubegreen/internal/controller"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	metricsInterval = 2 * time.Second
+	sparklineWidth  = 20
+	// metricsPageSize is the number of rows shown around the cursor
+	metricsPageSize = 25
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// metricsSortColumns are the orders of the metrics table, cycled with s
+var metricsSortColumns = []string{"name", "cpu", "cpu p95", "memory", "memory p95"}
+
+// metricsTickMsg triggers a collection. Ticks of an earlier visit of the
+// metrics view carry an older id and are dropped
+type metricsTickMsg struct {
+	id int
+}
+
+func (m *Model) metricsTick() tea.Cmd {
+	id := m.metricsTickID
+	return tea.Tick(metricsInterval, func(time.Time) tea.Msg {
+		return metricsTickMsg{id: id}
+	})
+}
+
+// startMetrics opens the metrics view and starts collecting
+func (m *Model) startMetrics() tea.Cmd {
+	m.State = MetricsView
+	m.Cursor = 0
+	m.metricsTickID++
+	m.Message = m.handleMetrics()
+	return m.metricsTick()
+}
+
+func (m *Model) handleMetricsTick(msg metricsTickMsg) (tea.Model, tea.Cmd) {
+	if m.State != MetricsView || msg.id != m.metricsTickID {
+		return m, nil
+	}
+	m.Message = m.handleMetrics()
+	return m, m.metricsTick()
+}
+
+func (m *Model) handleMetricsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "esc", "backspace":
+		m.State = MainMenu
+		m.Cursor = m.lastMainCursor
+		m.Message = ""
+	case "up", "k":
+		if m.Cursor > 0 {
+			m.Cursor--
+		}
+	case "down", "j":
+		if m.Cursor < len(m.metricsRows())-1 {
+			m.Cursor++
+		}
+	case "s":
+		m.metricsSort = (m.metricsSort + 1) % len(metricsSortColumns)
+		m.Cursor = 0
+	case "c":
+		m.metricsContainers = !m.metricsContainers
+		m.Cursor = 0
+	}
+	return m, nil
+}
+
+// metricsRows returns the pod series, or the container series, in the
+// order of the selected sort column
+func (m *Model) metricsRows() []controller.MetricsSeries {
+	var rows []controller.MetricsSeries
+	for _, series := range m.metricsHistory {
+		if (series.Container != "") == m.metricsContainers {
+			rows = append(rows, series)
+		}
+	}
+
+	// Usage columns sort the heaviest first, ties and name sort by key
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := &rows[i], &rows[j]
+		switch metricsSortColumns[m.metricsSort] {
+		case "cpu":
+			return a.Latest().CPU > b.Latest().CPU
+		case "cpu p95":
+			return a.CPU.P95 > b.CPU.P95
+		case "memory":
+			return a.Latest().Memory > b.Latest().Memory
+		case "memory p95":
+			return a.Memory.P95 > b.Memory.P95
+		}
+		return false
+	})
+	return rows
+}
+
+func (m *Model) renderMetrics() string {
+	var b strings.Builder
+	if m.metrics != nil {
+		sys := m.metrics.System
+		b.WriteString(fmt.Sprintf("Cluster: CPU %dm of %dm (%.1f%%), memory %dMB of %dMB (%.1f%%), updated %s\n",
+			sys.UsedCPU, sys.TotalCPUCapacity, sys.CPUUsagePercent,
+			sys.UsedMemory, sys.TotalMemoryCapacity, sys.MemoryUsagePercent, m.metrics.Timestamp))
+	}
+	window := time.Duration(m.config.MetricsWindowMinutes) * time.Minute
+	level := "pods"
+	if m.metricsContainers {
+		level = "containers"
+	}
+	b.WriteString(fmt.Sprintf("Last %s of %s, sorted by %s\n\n", window, level, metricsSortColumns[m.metricsSort]))
+
+	rows := m.metricsRows()
+	if len(rows) == 0 {
+		b.WriteString("No metrics collected yet\n")
+		return b.String()
+	}
+
+	name := "POD"
+	if m.metricsContainers {
+		name = "POD/CONTAINER"
+	}
+	b.WriteString(fmt.Sprintf("  %-20s %-45s %6s %6s %6s %6s %6s %-*s %6s %6s %6s %6s %6s %s\n",
+		"NAMESPACE", name,
+		"CPU m", "MIN", "AVG", "MAX", "P95", sparklineWidth, "",
+		"MEM MB", "MIN", "AVG", "MAX", "P95", ""))
+
+	start := bound(m.Cursor-metricsPageSize/2, 0, len(rows)-metricsPageSize)
+	start = bound(start, 0, len(rows)-1)
+	end := bound(start+metricsPageSize, 0, len(rows))
+	for i := start; i < end; i++ {
+		series := &rows[i]
+		cursor := " "
+		if m.Cursor == i {
+			cursor = ">"
+		}
+		label := series.Pod
+		if series.Container != "" {
+			label = series.Pod + "/" + series.Container
+		}
+		latest := series.Latest()
+		b.WriteString(fmt.Sprintf("%s %-20s %-45s %6d %6d %6.0f %6d %6d %s %6d %6d %6.0f %6d %6d %s\n",
+			cursor, series.Namespace, label,
+			latest.CPU, series.CPU.Min, series.CPU.Avg, series.CPU.Max, series.CPU.P95,
+			sparkline(series.CPUValues(), sparklineWidth),
+			latest.Memory, series.Memory.Min, series.Memory.Avg, series.Memory.Max, series.Memory.P95,
+			sparkline(series.MemoryValues(), sparklineWidth)))
+	}
+	if end-start < len(rows) {
+		b.WriteString(fmt.Sprintf("\n%d-%d of %d\n", start+1, end, len(rows)))
+	}
+	return b.String()
+}
+
+// sparkline draws values as block characters, averaging them into at most
+// width buckets and scaling between their min and max
+func sparkline(values []int64, width int) string {
+	if len(values) == 0 {
+		return strings.Repeat(" ", width)
+	}
+	buckets := len(values)
+	if buckets > width {
+		buckets = width
+	}
+	points := make([]float64, buckets)
+	for i := range points {
+		from, to := i*len(values)/buckets, (i+1)*len(values)/buckets
+		var sum int64
+		for _, v := range values[from:to] {
+			sum += v
+		}
+		points[i] = float64(sum) / float64(to-from)
+	}
+
+	min, max := points[0], points[0]
+	for _, p := range points {
+		if p < min {
+			min = p
+		}
+		if p > max {
+			max = p
+		}
+	}
+	var b strings.Builder
+	for _, p := range points {
+		level := 0
+		if max > min {
+			level = int((p - min) / (max - min) * float64(len(sparkBlocks)-1))
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	// Pad short histories so the columns stay aligned
+	b.WriteString(strings.Repeat(" ", width-buckets))
+	return b.String()
+}
@@ -0,0 +1,26 @@
+package model
+
+import "testing"
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int64
+		width  int
+		want   string
+	}{
+		{"no samples", nil, 4, "    "},
+		{"flat", []int64{5, 5, 5}, 5, "▁▁▁  "},
+		{"one value per block", []int64{0, 1, 2, 3, 4, 5, 6, 7}, 8, "▁▂▃▄▅▆▇█"},
+		{"scaled between min and max", []int64{100, 107, 114}, 3, "▁▄█"},
+		{"short history padded", []int64{0, 7}, 4, "▁█  "},
+		// Pairs average to 0.5, 2.5, 4.5 and 6.5
+		{"averaged into buckets", []int64{0, 1, 2, 3, 4, 5, 6, 7}, 4, "▁▃▅█"},
+		{"uneven buckets", []int64{0, 0, 0, 9, 9}, 2, "▁█"},
+	}
+	for _, tt := range tests {
+		if got := sparkline(tt.values, tt.width); got != tt.want {
+			t.Errorf("%s: sparkline(%v, %d) = %q, want %q", tt.name, tt.values, tt.width, got, tt.want)
+		}
+	}
+}
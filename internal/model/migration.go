@@ -0,0 +1,86 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"kubegreen/internal/controller"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (m *Model) loadMigrationClasses() {
+	classes, err := m.volumeCtl.ListStorageClasses()
+	if err != nil {
+		m.Message = fmt.Sprintf("Error listing storage classes: %v", err)
+		return
+	}
+
+	m.storageClasses = nil
+	for _, class := range classes {
+		if class.Name != m.selectedVolume.StorageClass {
+			m.storageClasses = append(m.storageClasses, class)
+		}
+	}
+	if len(m.storageClasses) == 0 {
+		m.Message = "No other StorageClass to migrate to"
+		return
+	}
+	m.State = MigrationClassMenu
+	m.Cursor = 0
+	m.Message = "Select the StorageClass to migrate to"
+}
+
+func (m *Model) migrateSelectedVolume(className string) tea.Cmd {
+	namespace, name, volumeCtl := m.selectedVolume.Namespace, m.selectedVolume.Name, m.volumeCtl
+	var migration *controller.VolumeMigration
+
+	title := fmt.Sprintf("Migrating volume %s/%s to StorageClass %s", namespace, name, className)
+	return m.startOperation(title, func(ctx context.Context, progress controller.ProgressFunc) error {
+		var err error
+		migration, err = volumeCtl.MigrateVolume(ctx, namespace, name, className, progress)
+		return err
+	}, func(err error) {
+		if err != nil {
+			m.Message = fmt.Sprintf("Failed to migrate volume:\n%v", err)
+			m.backToVolumeList()
+			return
+		}
+		m.Message = fmt.Sprintf("Workloads now use %s. Delete the old PVC %s now? (y/n)", migration.TargetPVC, migration.SourcePVC)
+		m.State = MigrationFinalizeConfirm
+	})
+}
+
+func (m *Model) finalizeMigration() tea.Cmd {
+	namespace, name, volumeCtl := m.selectedVolume.Namespace, m.selectedVolume.Name, m.volumeCtl
+	title := fmt.Sprintf("Finalizing migration of %s/%s", namespace, name)
+	return m.startOperation(title, func(ctx context.Context, progress controller.ProgressFunc) error {
+		return volumeCtl.FinalizeMigration(ctx, namespace, name, progress)
+	}, func(err error) {
+		if err != nil {
+			m.Message = fmt.Sprintf("Failed to finalize migration:\n%v", err)
+		} else {
+			m.Message = fmt.Sprintf("Deleted the migrated PVC %s/%s", namespace, name)
+		}
+		m.backToVolumeList()
+	})
+}
+
+func (m *Model) renderMigrationClasses() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Migrate %s/%s (StorageClass %s) to:\n\n",
+		m.selectedVolume.Namespace, m.selectedVolume.Name, m.selectedVolume.StorageClass))
+	for i, class := range m.storageClasses {
+		cursor := " "
+		if m.Cursor == i {
+			cursor = ">"
+		}
+		def := ""
+		if class.IsDefault {
+			def = " (default)"
+		}
+		b.WriteString(fmt.Sprintf("%s %s%s - %s\n", cursor, class.Name, def, class.Provisioner))
+	}
+	return b.String()
+}
@@ -0,0 +1,247 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"kubegreen/internal/controller"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const spinnerInterval = 100 * time.Millisecond
+
+type operationEventMsg controller.ProgressEvent
+
+type operationDoneMsg struct {
+	err error
+}
+
+// spinnerTickMsg advances the spinner of an operation. Ticks of an earlier
+// operation carry an older id and are dropped
+type spinnerTickMsg struct {
+	id int
+}
+
+type operationStep struct {
+	message  string
+	started  time.Time
+	finished time.Time
+	done     bool
+	details  []string
+}
+
+// operation is a long-running controller call executed in the background
+// while its progress events are rendered as a live step list
+type operation struct {
+	id       int
+	title    string
+	steps    []operationStep
+	started  time.Time
+	finished time.Time
+	running  bool
+	err      error
+	frame    int
+
+	cancel context.CancelFunc
+	events chan controller.ProgressEvent
+	result chan error
+
+	// onDone is called with the operation result once the user leaves the
+	// operation view
+	onDone func(err error)
+}
+
+// startOperation runs fn in the background and switches to the operation
+// view. fn must not touch the model, it only reports through progress
+func (m *Model) startOperation(title string, fn func(ctx context.Context, progress controller.ProgressFunc) error, onDone func(err error)) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.operationID++
+	op := &operation{
+		id:      m.operationID,
+		title:   title,
+		started: time.Now(),
+		running: true,
+		cancel:  cancel,
+		events:  make(chan controller.ProgressEvent, 64),
+		result:  make(chan error, 1),
+		onDone:  onDone,
+	}
+
+	go func() {
+		err := fn(ctx, func(event controller.ProgressEvent) {
+			op.events <- event
+		})
+		close(op.events)
+		op.result <- err
+	}()
+
+	m.operation = op
+	m.operationReturnState = m.State
+	m.State = OperationView
+	m.Message = ""
+	return tea.Batch(op.waitForEvent(), op.spinnerTick())
+}
+
+func (op *operation) waitForEvent() tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-op.events
+		if !ok {
+			return operationDoneMsg{err: <-op.result}
+		}
+		return operationEventMsg(event)
+	}
+}
+
+func (op *operation) spinnerTick() tea.Cmd {
+	id := op.id
+	return tea.Tick(spinnerInterval, func(time.Time) tea.Msg {
+		return spinnerTickMsg{id: id}
+	})
+}
+
+func (op *operation) apply(event controller.ProgressEvent) {
+	switch event.Type {
+	case controller.ProgressStep:
+		op.finishStep(event.Time)
+		op.steps = append(op.steps, operationStep{message: event.Message, started: event.Time})
+	case controller.ProgressSuccess:
+		op.finishStep(event.Time)
+		op.steps = append(op.steps, operationStep{
+			message:  event.Message,
+			started:  event.Time,
+			finished: event.Time,
+			done:     true,
+		})
+	case controller.ProgressDetail:
+		if len(op.steps) == 0 {
+			op.steps = append(op.steps, operationStep{message: op.title, started: event.Time})
+		}
+		last := &op.steps[len(op.steps)-1]
+		last.details = append(last.details, event.Message)
+	}
+}
+
+func (op *operation) finishStep(at time.Time) {
+	if len(op.steps) == 0 {
+		return
+	}
+	last := &op.steps[len(op.steps)-1]
+	if !last.done {
+		last.done = true
+		last.finished = at
+	}
+}
+
+func (m *Model) handleOperationMsg(msg tea.Msg) (tea.Model, tea.Cmd) {
+	op := m.operation
+	if op == nil {
+		return m, nil
+	}
+
+	switch msg := msg.(type) {
+	case operationEventMsg:
+		op.apply(controller.ProgressEvent(msg))
+		return m, op.waitForEvent()
+	case operationDoneMsg:
+		op.running = false
+		op.err = msg.err
+		op.finished = time.Now()
+		if msg.err == nil {
+			op.finishStep(op.finished)
+		}
+		op.cancel()
+	case spinnerTickMsg:
+		if op.running && msg.id == op.id {
+			op.frame = (op.frame + 1) % len(spinnerFrames)
+			return m, op.spinnerTick()
+		}
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			op.cancel()
+			return m, tea.Quit
+		case "c", "esc":
+			if op.running {
+				op.cancel()
+				return m, nil
+			}
+			m.closeOperation()
+		case "enter", "backspace", "q":
+			if !op.running {
+				m.closeOperation()
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *Model) closeOperation() {
+	op := m.operation
+	m.operation = nil
+	m.State = m.operationReturnState
+	if op.onDone != nil {
+		op.onDone(op.err)
+	}
+}
+
+func (m *Model) renderOperation() string {
+	op := m.operation
+	if op == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	end := time.Now()
+	if !op.running {
+		end = op.finished
+	}
+	b.WriteString(fmt.Sprintf("%s (%s)\n\n", op.title, formatElapsed(end.Sub(op.started))))
+
+	for i, step := range op.steps {
+		icon := "✓"
+		stepEnd := step.finished
+		switch {
+		case !step.done && op.running:
+			icon = spinnerFrames[op.frame]
+			stepEnd = time.Now()
+		case !step.done:
+			icon = "✗"
+			stepEnd = op.finished
+		}
+
+		// Success events mark a point in time, not a step with a duration
+		if step.finished.Equal(step.started) && step.done {
+			b.WriteString(fmt.Sprintf("%s %s\n", icon, step.message))
+		} else {
+			b.WriteString(fmt.Sprintf("%s %s (%s)\n", icon, step.message, formatElapsed(stepEnd.Sub(step.started))))
+		}
+		for _, detail := range step.details {
+			b.WriteString(fmt.Sprintf("    └─ %s\n", detail))
+		}
+		if i == len(op.steps)-1 && !op.running && op.err != nil {
+			b.WriteString(fmt.Sprintf("\n%s\n", op.err))
+		}
+	}
+	if len(op.steps) == 0 && !op.running && op.err != nil {
+		b.WriteString(fmt.Sprintf("%s\n", op.err))
+	}
+
+	if op.running {
+		b.WriteString("\n(c or esc to cancel)\n")
+	} else {
+		b.WriteString("\n(enter to continue)\n")
+	}
+	return b.String()
+}
+
+func formatElapsed(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return d.Round(time.Second).String()
+}
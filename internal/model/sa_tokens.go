@@ -0,0 +1,157 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (m *Model) isTokenState() bool {
+	return m.State == TokenListView || m.State == TokenDeleteInput
+}
+
+func (m *Model) loadTokens() {
+	tokens, err := m.certCtl.AuditServiceAccountTokens()
+	if err != nil {
+		m.Message = fmt.Sprintf("Error auditing service account tokens: %v", err)
+		return
+	}
+	m.tokens = tokens
+	m.State = TokenListView
+	m.Cursor = 0
+}
+
+func (m *Model) handleTokens(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.State == TokenDeleteInput {
+		return m.handleTokenDeleteInput(msg)
+	}
+
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.Cursor > 0 {
+			m.Cursor--
+		}
+	case "down", "j":
+		if m.Cursor < len(m.tokens)-1 {
+			m.Cursor++
+		}
+	case "r":
+		cursor := m.Cursor
+		m.Message = ""
+		m.loadTokens()
+		m.Cursor = bound(cursor, 0, len(m.tokens)-1)
+	case "d":
+		if len(m.tokens) == 0 {
+			return m, nil
+		}
+		token := m.tokens[m.Cursor]
+		if len(token.UsedBy) > 0 {
+			m.Message = fmt.Sprintf("Secret %s is used by %s, it cannot be deleted", token.Name, strings.Join(token.UsedBy, ", "))
+			return m, nil
+		}
+		m.tokenConfirm = ""
+		m.Message = fmt.Sprintf("Clients still holding the token of %s/%s will be locked out.\nType the secret name to confirm:",
+			token.Namespace, token.Name)
+		m.State = TokenDeleteInput
+	case "esc", "backspace":
+		m.State = MainMenu
+		m.Cursor = m.lastMainCursor
+		m.Message = ""
+	}
+	return m, nil
+}
+
+func (m *Model) handleTokenDeleteInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	token := m.tokens[m.Cursor]
+
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.Message = "Token kept"
+		m.State = TokenListView
+	case tea.KeyEnter:
+		m.State = TokenListView
+		if m.tokenConfirm != token.Name {
+			m.Message = fmt.Sprintf("The name does not match %s, token kept", token.Name)
+			return m, nil
+		}
+		if err := m.certCtl.DeleteServiceAccountToken(&token); err != nil {
+			m.Message = fmt.Sprintf("Failed to delete token:\n%v", err)
+			return m, nil
+		}
+		cursor := m.Cursor
+		m.loadTokens()
+		m.Cursor = bound(cursor, 0, len(m.tokens)-1)
+		m.Message = fmt.Sprintf("Deleted token secret %s/%s", token.Namespace, token.Name)
+	default:
+		m.tokenConfirm = editText(m.tokenConfirm, msg)
+	}
+	return m, nil
+}
+
+func (m *Model) renderTokens() string {
+	var b strings.Builder
+	if len(m.tokens) == 0 {
+		b.WriteString("No service account token secrets found\n")
+		return b.String()
+	}
+
+	flagged := 0
+	for i := range m.tokens {
+		if len(m.tokens[i].Problems()) > 0 {
+			flagged++
+		}
+	}
+	b.WriteString(fmt.Sprintf("%d token secrets, %d flagged\n\n", len(m.tokens), flagged))
+
+	b.WriteString(fmt.Sprintf("  %-25s %-45s %-30s %-8s %-16s %s\n", "NAMESPACE", "SECRET", "SERVICE ACCOUNT", "AGE", "EXPIRES", "PROBLEMS"))
+	for i := range m.tokens {
+		token := &m.tokens[i]
+		cursor := " "
+		if m.Cursor == i {
+			cursor = ">"
+		}
+		expires := "never"
+		if !token.Expires.IsZero() {
+			expires = token.Expires.Format("2006-01-02 15:04")
+		} else if token.TokenError != "" {
+			expires = "-"
+		}
+		problems := token.Problems()
+		line := fmt.Sprintf("%s %-25s %-45s %-30s %-8s %-16s %s", cursor, token.Namespace, token.Name,
+			orDash(token.ServiceAccount), token.Age, expires, orDash(strings.Join(problems, ", ")))
+		if len(problems) > 0 {
+			line = warningStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	token := &m.tokens[m.Cursor]
+	b.WriteString(fmt.Sprintf("\nToken %s/%s:\n", token.Namespace, token.Name))
+	b.WriteString(fmt.Sprintf("  Service account: %s\n", orDash(token.ServiceAccount)))
+	b.WriteString(fmt.Sprintf("  Created:         %s (%s ago)\n", token.Created.Format("2006-01-02 15:04"), token.Age))
+	if token.TokenError != "" {
+		b.WriteString(fmt.Sprintf("  Token:           %s\n", warningStyle.Render(token.TokenError)))
+	} else {
+		b.WriteString(fmt.Sprintf("  Issuer:          %s\n", orDash(token.Issuer)))
+		b.WriteString(fmt.Sprintf("  Subject:         %s\n", orDash(token.Subject)))
+		if !token.IssuedAt.IsZero() {
+			b.WriteString(fmt.Sprintf("  Issued:          %s\n", token.IssuedAt.Format("2006-01-02 15:04")))
+		}
+		if token.Expires.IsZero() {
+			b.WriteString(fmt.Sprintf("  Expires:         %s\n", warningStyle.Render("never")))
+		} else {
+			b.WriteString(fmt.Sprintf("  Expires:         %s\n", token.Expires.Format("2006-01-02 15:04")))
+		}
+	}
+	b.WriteString(fmt.Sprintf("  Used by:         %s\n", orDash(strings.Join(token.UsedBy, ", "))))
+
+	if m.State == TokenDeleteInput {
+		b.WriteString(fmt.Sprintf("\nSecret name: %s_\n", m.tokenConfirm))
+	}
+	return b.String()
+}
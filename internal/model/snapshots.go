@@ -0,0 +1,124 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"kubegreen/internal/controller"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (m *Model) loadSnapshotClasses() {
+	classes, err := m.volumeCtl.ListSnapshotClasses()
+	if err != nil {
+		m.Message = fmt.Sprintf("Error listing snapshot classes: %v", err)
+		return
+	}
+	if len(classes) == 0 {
+		m.Message = "No VolumeSnapshotClass found"
+		return
+	}
+	m.snapshotClasses = classes
+	m.State = SnapshotClassMenu
+	m.Cursor = 0
+	m.Message = "Select a snapshot class"
+}
+
+func (m *Model) loadSnapshots() {
+	snapshots, err := m.volumeCtl.ListSnapshots(m.selectedVolume.Namespace, m.selectedVolume.Name)
+	if err != nil {
+		m.Message = fmt.Sprintf("Error listing snapshots: %v", err)
+		return
+	}
+	if len(snapshots) == 0 {
+		m.Message = fmt.Sprintf("No snapshots of %s/%s found", m.selectedVolume.Namespace, m.selectedVolume.Name)
+		return
+	}
+	m.snapshots = snapshots
+	m.State = SnapshotListMenu
+	m.Cursor = 0
+	m.Message = "Select a snapshot to restore into a new PVC"
+}
+
+func (m *Model) createSnapshot(className string) tea.Cmd {
+	namespace, name, volumeCtl := m.selectedVolume.Namespace, m.selectedVolume.Name, m.volumeCtl
+	title := fmt.Sprintf("Creating snapshot of %s/%s", namespace, name)
+	return m.startOperation(title, func(ctx context.Context, progress controller.ProgressFunc) error {
+		_, err := volumeCtl.CreateSnapshot(ctx, namespace, name, className, progress)
+		return err
+	}, func(err error) {
+		if err != nil {
+			m.Message = fmt.Sprintf("Failed to create snapshot:\n%v", err)
+		} else {
+			m.Message = fmt.Sprintf("Created snapshot of %s/%s", namespace, name)
+		}
+		m.backToVolumeList()
+	})
+}
+
+func (m *Model) handleSnapshotRestoreInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.Message = "Snapshot restore cancelled"
+		m.backToVolumeList()
+	case tea.KeyEnter:
+		if m.snapshotPVCName == "" || m.selectedSnapshot == nil {
+			return m, nil
+		}
+		namespace, snapshot, pvcName := m.selectedSnapshot.Namespace, m.selectedSnapshot.Name, m.snapshotPVCName
+		volumeCtl := m.volumeCtl
+		title := fmt.Sprintf("Restoring snapshot %s/%s into %s", namespace, snapshot, pvcName)
+		return m, m.startOperation(title, func(ctx context.Context, progress controller.ProgressFunc) error {
+			return volumeCtl.RestoreSnapshot(ctx, namespace, snapshot, pvcName, progress)
+		}, func(err error) {
+			if err != nil {
+				m.Message = fmt.Sprintf("Failed to restore snapshot:\n%v", err)
+			} else {
+				m.Message = fmt.Sprintf("Restored snapshot %s into PVC %s/%s", snapshot, namespace, pvcName)
+			}
+			m.backToVolumeList()
+		})
+	default:
+		m.snapshotPVCName = editText(m.snapshotPVCName, msg)
+	}
+	return m, nil
+}
+
+func (m *Model) renderSnapshotClasses() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Snapshot %s/%s with class:\n\n", m.selectedVolume.Namespace, m.selectedVolume.Name))
+	for i, class := range m.snapshotClasses {
+		cursor := " "
+		if m.Cursor == i {
+			cursor = ">"
+		}
+		def := ""
+		if class.IsDefault {
+			def = " (default)"
+		}
+		b.WriteString(fmt.Sprintf("%s %s%s - %s, deletion policy %s\n", cursor, class.Name, def, class.Driver, class.DeletionPolicy))
+	}
+	return b.String()
+}
+
+func (m *Model) renderSnapshots() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Snapshots of %s/%s:\n\n", m.selectedVolume.Namespace, m.selectedVolume.Name))
+	for i, snapshot := range m.snapshots {
+		cursor := " "
+		if m.Cursor == i {
+			cursor = ">"
+		}
+		ready := "not ready"
+		if snapshot.ReadyToUse {
+			ready = "ready"
+		}
+		b.WriteString(fmt.Sprintf("%s %-50s %s  %8s  %s\n", cursor, snapshot.Name,
+			snapshot.CreationTime.Format("2006-01-02 15:04:05"), snapshot.RestoreSize, ready))
+	}
+	return b.String()
+}
@@ -0,0 +1,153 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"kubegreen/internal/controller"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func (m *Model) loadStorageWaste() {
+	report, err := m.volumeCtl.FindStorageWaste()
+	if err != nil {
+		m.Message = fmt.Sprintf("Error finding storage waste: %v", err)
+		m.State = MainMenu
+		m.Cursor = m.lastMainCursor
+		return
+	}
+	m.wasteReport = report
+	m.wasteMarked = make(map[int]bool)
+	m.State = StorageWasteView
+	m.Cursor = 0
+	if len(report.Items) == 0 {
+		m.Message = "No storage waste found"
+	}
+}
+
+func (m *Model) handleStorageWaste(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.State == StorageWasteView && m.Cursor > 0 {
+			m.Cursor--
+		}
+	case "down", "j":
+		if m.State == StorageWasteView && m.Cursor < len(m.wasteReport.Items)-1 {
+			m.Cursor++
+		}
+	case " ":
+		if m.State == StorageWasteView && len(m.wasteReport.Items) > 0 {
+			m.wasteMarked[m.Cursor] = !m.wasteMarked[m.Cursor]
+		}
+	case "a":
+		if m.State == StorageWasteView {
+			allMarked := len(m.markedWaste()) == len(m.wasteReport.Items)
+			for i := range m.wasteReport.Items {
+				m.wasteMarked[i] = !allMarked
+			}
+		}
+	case "d":
+		if m.State == StorageWasteView {
+			marked := m.markedWaste()
+			if len(marked) == 0 {
+				m.Message = "Mark items with space (or a for all) before cleaning up"
+				return m, nil
+			}
+			m.Message = fmt.Sprintf("Delete %d marked item(s)? (y/n)", len(marked))
+			m.State = StorageWasteConfirm
+		}
+	case "y", "Y":
+		if m.State == StorageWasteConfirm {
+			return m, m.cleanupStorageWaste()
+		}
+	case "n", "N":
+		if m.State == StorageWasteConfirm {
+			m.Message = "Cleanup cancelled"
+			m.State = StorageWasteView
+		}
+	case "esc", "backspace":
+		if m.State == StorageWasteConfirm {
+			m.Message = ""
+			m.State = StorageWasteView
+			return m, nil
+		}
+		m.State = MainMenu
+		m.Cursor = m.lastMainCursor
+		m.Message = ""
+	}
+	return m, nil
+}
+
+func (m *Model) markedWaste() []controller.WasteItem {
+	var marked []controller.WasteItem
+	for i, item := range m.wasteReport.Items {
+		if m.wasteMarked[i] {
+			marked = append(marked, item)
+		}
+	}
+	return marked
+}
+
+func (m *Model) cleanupStorageWaste() tea.Cmd {
+	items, volumeCtl := m.markedWaste(), m.volumeCtl
+	title := fmt.Sprintf("Cleaning up %d storage item(s)", len(items))
+	return m.startOperation(title, func(ctx context.Context, progress controller.ProgressFunc) error {
+		return volumeCtl.CleanupStorageWaste(ctx, items, progress)
+	}, func(err error) {
+		m.loadStorageWaste()
+		if err != nil {
+			m.Message = fmt.Sprintf("Cleanup finished with errors:\n%v", err)
+		} else {
+			m.Message = fmt.Sprintf("Cleaned up %d item(s)", len(items))
+		}
+	})
+}
+
+func formatBytes(bytes int64) string {
+	return resource.NewQuantity(bytes, resource.BinarySI).String()
+}
+
+func (m *Model) renderStorageWaste() string {
+	var b strings.Builder
+	report := m.wasteReport
+
+	b.WriteString("Storage waste:\n\n")
+	for _, category := range controller.WasteCategories {
+		count := 0
+		for _, item := range report.Items {
+			if item.Category == category {
+				count++
+			}
+		}
+		b.WriteString(fmt.Sprintf("  %-28s %3d item(s) %10s\n", category, count, formatBytes(report.TotalBytes[category])))
+	}
+	b.WriteRune('\n')
+
+	var lastCategory controller.WasteCategory
+	for i, item := range report.Items {
+		if item.Category != lastCategory {
+			b.WriteString(fmt.Sprintf("%s:\n", item.Category))
+			lastCategory = item.Category
+		}
+
+		cursor := " "
+		if m.Cursor == i {
+			cursor = ">"
+		}
+		mark := "[ ]"
+		if m.wasteMarked[i] {
+			mark = "[x]"
+		}
+		name := item.Name
+		if item.Namespace != "" {
+			name = item.Namespace + "/" + item.Name
+		}
+		b.WriteString(fmt.Sprintf("%s %s %-50s %8s  %s\n", cursor, mark, name, item.Capacity, item.Reason))
+	}
+	return b.String()
+}
@@ -0,0 +1,194 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"kubegreen/internal/controller"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (m *Model) isDiagnosisState() bool {
+	return m.State == StuckDiagnosisView || m.State == FinalizerRemoveInput
+}
+
+// diagnose runs fn and shows its result in the diagnosis view, returning to
+// the current view afterwards
+func (m *Model) diagnose(fn func() (*controller.StuckDiagnosis, error)) {
+	d, err := fn()
+	if err != nil {
+		m.Message = fmt.Sprintf("Failed to diagnose volume:\n%v", err)
+		return
+	}
+	if !m.isDiagnosisState() {
+		m.diagnosisReturnState = m.State
+		m.diagnosisReturnCursor = m.Cursor
+	}
+	m.diagnosis = d
+	m.State = StuckDiagnosisView
+	m.Cursor = 0
+}
+
+func (m *Model) rediagnose() {
+	d := m.diagnosis
+	if d.PVCName != "" {
+		m.diagnose(func() (*controller.StuckDiagnosis, error) {
+			return m.volumeCtl.DiagnosePVC(d.Namespace, d.PVCName)
+		})
+		return
+	}
+	m.diagnose(func() (*controller.StuckDiagnosis, error) {
+		return m.volumeCtl.DiagnosePV(d.PVName)
+	})
+}
+
+func (m *Model) closeDiagnosis() {
+	m.diagnosis = nil
+	m.finalizerConfirm = ""
+	switch m.diagnosisReturnState {
+	case PVListView:
+		cursor := m.diagnosisReturnCursor
+		m.loadPersistentVolumes()
+		m.Cursor = bound(cursor, 0, len(m.persistentVols)-1)
+	default:
+		m.backToVolumeList()
+	}
+}
+
+func (m *Model) handleDiagnosis(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.State == FinalizerRemoveInput {
+		return m.handleFinalizerRemoveInput(msg)
+	}
+
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.Cursor > 0 {
+			m.Cursor--
+		}
+	case "down", "j":
+		if m.Cursor < len(m.diagnosis.Finalizers)-1 {
+			m.Cursor++
+		}
+	case "r":
+		m.Message = ""
+		m.rediagnose()
+	case "enter":
+		if len(m.diagnosis.Finalizers) > 0 {
+			ref := m.diagnosis.Finalizers[m.Cursor]
+			m.finalizerConfirm = ""
+			m.Message = fmt.Sprintf("Removing %s skips the cleanup it guards.\nType the finalizer name to confirm:", ref)
+			m.State = FinalizerRemoveInput
+		}
+	case "esc", "backspace":
+		m.Message = ""
+		m.closeDiagnosis()
+	}
+	return m, nil
+}
+
+func (m *Model) handleFinalizerRemoveInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	ref := m.diagnosis.Finalizers[m.Cursor]
+
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.Message = "Finalizer kept"
+		m.State = StuckDiagnosisView
+	case tea.KeyEnter:
+		if m.finalizerConfirm != ref.Finalizer {
+			m.Message = fmt.Sprintf("The name does not match %s, finalizer kept", ref.Finalizer)
+			m.State = StuckDiagnosisView
+			return m, nil
+		}
+		if err := m.volumeCtl.RemoveFinalizer(ref); err != nil {
+			m.Message = fmt.Sprintf("Failed to remove finalizer:\n%v", err)
+			m.State = StuckDiagnosisView
+			return m, nil
+		}
+		m.Message = fmt.Sprintf("Removed %s", ref)
+		m.rediagnose()
+		if m.State == FinalizerRemoveInput {
+			// The object was deleted once its last finalizer was removed
+			m.closeDiagnosis()
+			m.Message = fmt.Sprintf("Removed %s, the object is gone", ref)
+		}
+	default:
+		m.finalizerConfirm = editText(m.finalizerConfirm, msg)
+	}
+	return m, nil
+}
+
+func (m *Model) renderDiagnosis() string {
+	var b strings.Builder
+	d := m.diagnosis
+
+	if d.PVCName != "" {
+		b.WriteString(fmt.Sprintf("PVC %s/%s", d.Namespace, d.PVCName))
+		if d.PVCDeleting {
+			b.WriteString(fmt.Sprintf(" (deletion requested %s ago)", formatElapsed(time.Since(d.PVCDeleted))))
+		}
+		b.WriteRune('\n')
+	}
+	if d.PVName != "" {
+		b.WriteString(fmt.Sprintf("PV %s", d.PVName))
+		if d.PVDeleting {
+			b.WriteString(fmt.Sprintf(" (deletion requested %s ago)", formatElapsed(time.Since(d.PVDeleted))))
+		}
+		b.WriteRune('\n')
+	}
+
+	b.WriteString("\nWhy:\n")
+	for _, reason := range d.Reasons {
+		b.WriteString(fmt.Sprintf("  - %s\n", reason))
+	}
+
+	if len(d.Pods) > 0 {
+		b.WriteString("\nPods referencing the PVC:\n")
+		for _, pod := range d.Pods {
+			state := pod.Phase
+			if pod.Terminating {
+				state = fmt.Sprintf("Terminating for %s", formatElapsed(time.Since(pod.Since)))
+			}
+			b.WriteString(fmt.Sprintf("  %-50s %-25s node %s\n", pod.Name, state, orDash(pod.Node)))
+		}
+	}
+
+	if len(d.VolumeAttachments) > 0 {
+		b.WriteString("\nVolumeAttachments:\n")
+		for _, va := range d.VolumeAttachments {
+			state := "detached"
+			if va.Attached {
+				state = "attached"
+			}
+			b.WriteString(fmt.Sprintf("  %-50s %-9s node %s (%s)\n", va.Name, state, va.Node, va.Attacher))
+			if va.AttachError != "" {
+				b.WriteString(fmt.Sprintf("    attach error: %s\n", va.AttachError))
+			}
+			if va.DetachError != "" {
+				b.WriteString(fmt.Sprintf("    detach error: %s\n", va.DetachError))
+			}
+		}
+	}
+
+	b.WriteString("\nFinalizers:\n")
+	if len(d.Finalizers) == 0 {
+		b.WriteString("  none\n")
+	}
+	for i, ref := range d.Finalizers {
+		cursor := " "
+		if m.Cursor == i {
+			cursor = ">"
+		}
+		b.WriteString(fmt.Sprintf("%s %s\n", cursor, ref))
+	}
+
+	if m.State == FinalizerRemoveInput {
+		b.WriteString(fmt.Sprintf("\nFinalizer: %s_\n", m.finalizerConfirm))
+	}
+	return b.String()
+}
@@ -30,6 +30,18 @@ var (
 	podNormalStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("252"))
 
+	warningStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Bold(true)
+
+	criticalStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Bold(true)
+
+	expiredStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("160")).
+			Strikethrough(true)
+
 	namespaceStyle = lipgloss.NewStyle().Width(30)
 	nameStyle      = lipgloss.NewStyle().Width(50)
 	readyStyle     = lipgloss.NewStyle().Width(10).Align(lipgloss.Right)
@@ -1,6 +1,12 @@
 package model
 
 import (
+	"context"
+	"crypto/x509/pkix"
+	"fmt"
+	"time"
+
+	"kubegreen/internal/config"
 	"kubegreen/internal/controller"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -15,6 +21,34 @@ const (
 	VolumeResizeMenu
 	VolumeSizeInput
 	MetricsView
+	VolumeActionMenu
+	VolumeDeleteConfirm
+	OperationView
+	StorageWasteView
+	StorageWasteConfirm
+	SnapshotClassMenu
+	SnapshotListMenu
+	SnapshotRestoreInput
+	MigrationClassMenu
+	MigrationFinalizeConfirm
+	PVListView
+	StorageClassListView
+	ReclaimPolicyConfirm
+	StuckDiagnosisView
+	FinalizerRemoveInput
+	CertDetailView
+	CertCAInput
+	RenewalForm
+	CSRListView
+	CSRDecisionInput
+	CertManagerRenewConfirm
+	LocalCAInput
+	IngressTLSView
+	IngressProbeInput
+	CertExportInput
+	TokenListView
+	TokenDeleteInput
+	WorkloadRestoreConfirm
 )
 
 type Model struct {
@@ -24,26 +58,90 @@ type Model struct {
 	State            MenuState
 	Message          string
 	contextCtl       *controller.ContextController
+	config           *config.Config
 	lastMainCursor   int
 	showDetails      bool
 	pods             []controller.PodInfo
 	selectedPodIndex int
-	renewalResponse  string
 
 	certCtl *controller.CertController
 
-	selectedCert *controller.CertInfo
-	certificates []controller.CertInfo
+	selectedCert   *controller.CertInfo
+	certificates   []controller.CertInfo
+	lastCertCursor int
+	certSortByName bool
+	// kubeconfigAudits holds the certificate audit of each kubeconfig
+	// context, by context name
+	kubeconfigAudits map[string]*controller.KubeconfigCertAudit
+	certCASecret     string
+	// certExport is the format and file typed to export the certificates
+	certExport string
+	// localCASource is the CA secret or files last used to re-issue a
+	// certificate locally
+	localCASource string
+	// certManager holds the cert-manager resources of selectedCert, nil for
+	// unmanaged secrets
+	certManager *controller.CertManagerInfo
+	// renewalForm holds the values of the renewal form, see renewalFields
+	renewalForm []string
+	// renewalSubject is the subject of the certificate being renewed, its
+	// CN and O are edited in the form
+	renewalSubject pkix.Name
+	renewal        *controller.RenewalRequest
+	csrs           []controller.CSRInfo
+	csrApprove     bool
+	csrReason      string
+	ingressTLS     []controller.IngressTLSEntry
+	// ingressProbes holds the last probe of each entry of ingressTLS, by
+	// index
+	ingressProbes       map[int][]controller.EndpointProbe
+	ingressProbeAddress string
+	tokens              []controller.ServiceAccountTokenInfo
+	tokenConfirm        string
 
 	// Volume-related fields
-	volumeCtl      *controller.VolumeController
-	volumes        []controller.VolumeInfo
-	selectedVolume *controller.VolumeInfo
-	newVolumeSize  string
+	volumeCtl        *controller.VolumeController
+	volumes          []controller.VolumeInfo
+	selectedVolume   *controller.VolumeInfo
+	newVolumeSize    string
+	lastVolumeCursor int
+	// volumeUsage is the last usage collected, by namespace/name, nil until
+	// the first collection ends
+	volumeUsage        map[string]controller.VolumeUsage
+	volumeUsageErr     error
+	volumeUsagePending bool
+	volumeUsageID      int
+	volumeUsageCancel  context.CancelFunc
+	volumePlan         *controller.VolumeDeletePlan
+	// pausedWorkloads are the workloads r offers to restore
+	pausedWorkloads  []controller.PausedWorkload
+	snapshotClasses  []controller.SnapshotClassInfo
+	snapshots        []controller.SnapshotInfo
+	selectedSnapshot *controller.SnapshotInfo
+	snapshotPVCName  string
+	storageClasses   []controller.StorageClassInfo
+	persistentVols   []controller.PersistentVolumeInfo
+	diagnosis        *controller.StuckDiagnosis
+	// diagnosisReturnState is the view the diagnosis was opened from
+	diagnosisReturnState  MenuState
+	diagnosisReturnCursor int
+	finalizerConfirm      string
+	wasteReport           *controller.StorageWasteReport
+	wasteMarked           map[int]bool
+
+	// Background operation shown in OperationView
+	operation            *operation
+	operationReturnState MenuState
+	operationID          int
 
 	// Metrics-related fields
 	metricsCtl *controller.MetricsController
 	metrics    *controller.MetricsOutput
+	// metricsHistory holds the series of every pod and container
+	metricsHistory    []controller.MetricsSeries
+	metricsSort       int
+	metricsContainers bool
+	metricsTickID     int
 }
 
 func NewModel() tea.Model {
@@ -55,16 +153,26 @@ func NewModel() tea.Model {
 		}
 	}
 
-	certCtl := controller.NewCertController(ctlr.GetClientset())
-	volumeCtl := controller.NewVolumeController(ctlr.GetClientset(), ctlr.GetConfig())
+	certCtl := controller.NewCertController(ctlr.GetClientset(), ctlr.GetDynamicClient())
+	volumeCtl := controller.NewVolumeController(ctlr.GetClientset(), ctlr.GetDynamicClient(), ctlr.GetConfig())
 	metricsCtl := controller.NewMetricsController(ctlr.GetClientset(), ctlr.GetMetricsClientset())
 
+	cfg, err := config.Load()
+	message := ""
+	if err != nil {
+		message = fmt.Sprintf("Using default settings: %v", err)
+	}
+	certCtl.SetExpiryThresholds(cfg.CertThresholdsFor)
+	metricsCtl.SetHistoryWindow(time.Duration(cfg.MetricsWindowMinutes)*time.Minute, metricsInterval)
+
 	return &Model{
-		Choices:    []string{"list", "contexts", "pod", "certificates", "volumes", "metrics"},
+		Choices:    []string{"list", "contexts", "pod", "certificates", "certificate requests", "ingress tls", "service account tokens", "volumes", "storage waste", "metrics"},
 		State:      MainMenu,
 		contextCtl: ctlr,
 		certCtl:    certCtl,
 		volumeCtl:  volumeCtl,
 		metricsCtl: metricsCtl,
+		config:     cfg,
+		Message:    message,
 	}
 }
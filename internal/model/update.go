@@ -1,46 +1,58 @@
 package model
 
-import (
-	time "time"
-
-	tea "github.com/charmbracelet/bubbletea"
-)
+import tea "github.com/charmbracelet/bubbletea"
 
 func (m *Model) Init() tea.Cmd {
 	if m.State == MetricsView {
-		return tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
-			return t
-		})
+		return m.metricsTick()
 	}
 	return nil
 }
 
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+	// Loading the volume list schedules the collection of their usage
+	if m.volumeUsagePending {
+		m.volumeUsagePending = false
+		cmd = tea.Batch(cmd, m.loadVolumeUsage())
+	}
+	return model, cmd
+}
+
+func (m *Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case operationEventMsg, operationDoneMsg, spinnerTickMsg:
+		return m.handleOperationMsg(msg)
 	case tea.KeyMsg:
-		if m.State == VolumeResizeMenu || m.State == VolumeSizeInput {
+		if m.State == OperationView {
+			return m.handleOperationMsg(msg)
+		}
+		if m.State == StorageWasteView || m.State == StorageWasteConfirm {
+			return m.handleStorageWaste(msg)
+		}
+		if m.isCertificateState() {
+			return m.handleCertificateMenu(msg)
+		}
+		if m.isCSRState() {
+			return m.handleCSRs(msg)
+		}
+		if m.isIngressTLSState() {
+			return m.handleIngressTLS(msg)
+		}
+		if m.isTokenState() {
+			return m.handleTokens(msg)
+		}
+		if m.isVolumeState() {
 			return m.handleVolumeMenu(msg)
 		}
 		if m.State == MetricsView {
-			switch msg.String() {
-			case "q", "ctrl+c", "esc", "backspace":
-				m.State = MainMenu
-				m.Cursor = m.lastMainCursor
-				m.Message = ""
-				return m, nil
-			}
-			return m, tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
-				return t
-			})
+			return m.handleMetricsKeys(msg)
 		}
 		return m.handleKeyPress(msg)
-	case time.Time:
-		if m.State == MetricsView {
-			m.Message = m.handleMetrics()
-			return m, tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
-				return t
-			})
-		}
+	case metricsTickMsg:
+		return m.handleMetricsTick(msg)
+	case volumeUsageMsg:
+		return m.handleVolumeUsage(msg)
 	}
 	return m, nil
 }
@@ -54,24 +66,26 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "down", "j":
 		m.moveCursor(1)
 	case "enter":
+		metrics := m.State == MainMenu && m.Choices[m.Cursor] == "metrics"
 		m.handleEnter()
+		if metrics {
+			return m, m.startMetrics()
+		}
+	case "s":
+		if m.State == ListSubMenu && m.Choices[m.lastMainCursor] == "certificates" {
+			m.toggleCertificateSort()
+		}
+	case "e":
+		if m.State == ListSubMenu && m.Choices[m.lastMainCursor] == "certificates" {
+			m.certExport = ""
+			m.Message = "Export as json, csv or markdown, optionally followed by a file:"
+			m.State = CertExportInput
+		}
 	case "backspace":
 		if m.State == ListSubMenu {
 			m.State = MainMenu
 			m.Cursor = 0
 		}
-
-	case "y", "Y":
-		if m.State == RenewalConfirm {
-			m.renewalResponse = "y"
-			m.handleEnter()
-		}
-	case "n", "N":
-		if m.State == RenewalConfirm {
-			m.renewalResponse = "n"
-			m.Message = "Certificate renewal cancelled"
-			m.State = MainMenu
-		}
 	}
 	return m, nil
 }
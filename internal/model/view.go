@@ -8,9 +8,17 @@ import (
 func (m *Model) View() string {
 	var b strings.Builder
 
+	if m.State == OperationView {
+		return m.renderOperation()
+	}
+
 	if m.State == MetricsView {
-		b.WriteString(m.Message)
-		b.WriteString("\n\n(↑/↓ or j/k to move, enter to select, q to quit)\n")
+		b.WriteString(m.renderMetrics())
+		if m.Message != "" {
+			b.WriteString(messageStyle.Render(m.Message))
+			b.WriteRune('\n')
+		}
+		b.WriteString("\n(↑/↓ or j/k to move, s to change the sort, c to switch between pods and containers, backspace to go back, q to quit)\n")
 		return b.String()
 	}
 
@@ -29,16 +37,36 @@ func (m *Model) View() string {
 	case ListSubMenu:
 		if m.Choices[m.lastMainCursor] == "pod" {
 			b.WriteString(renderChoices(m.SubChoices, m.Cursor, true))
+		} else if m.Choices[m.lastMainCursor] == "contexts" {
+			b.WriteString(m.renderContexts())
+		} else if m.Choices[m.lastMainCursor] == "certificates" {
+			b.WriteString(m.renderCertificateList())
 		} else {
 			b.WriteString("List sub-options:\n\n")
 			b.WriteString(renderChoices(m.SubChoices, m.Cursor, false))
 		}
 
-	case RenewalConfirm:
-		b.WriteString("Renewal Confirm\n\n")
+	case CertExportInput:
+		b.WriteString(m.renderCertificateList())
+		b.WriteString(fmt.Sprintf("\nExport: %s_\n", m.certExport))
+
+	case CertDetailView, CertCAInput, CertManagerRenewConfirm, LocalCAInput:
+		b.WriteString(m.renderCertificateDetails())
+
+	case RenewalForm, RenewalConfirm:
+		b.WriteString(m.renderRenewalForm())
+
+	case CSRListView, CSRDecisionInput:
+		b.WriteString(m.renderCSRs())
+
+	case IngressTLSView, IngressProbeInput:
+		b.WriteString(m.renderIngressTLS())
+
+	case TokenListView, TokenDeleteInput:
+		b.WriteString(m.renderTokens())
 
 	case VolumeResizeMenu:
-		b.WriteString("Select a volume to delete:\n\n")
+		b.WriteString(m.renderVolumeTabs())
 
 		for i, choice := range m.SubChoices {
 			cursor := " "
@@ -47,6 +75,67 @@ func (m *Model) View() string {
 			}
 			b.WriteString(fmt.Sprintf("%s %s\n", cursor, choice))
 		}
+
+	case VolumeActionMenu:
+		b.WriteString(fmt.Sprintf("Volume %s/%s (%s):\n",
+			m.selectedVolume.Namespace, m.selectedVolume.Name, m.selectedVolume.Size))
+		if usage := m.selectedVolume.Usage; usage != nil {
+			b.WriteString(fmt.Sprintf("  Used:      %s of %s (%.1f%%), %s available\n",
+				formatBytes(usage.UsedBytes), formatBytes(usage.CapacityBytes), usage.PercentUsed(), formatBytes(usage.AvailableBytes)))
+			b.WriteString(fmt.Sprintf("  Inodes:    %d of %d (%.1f%%), %d free\n",
+				usage.InodesUsed, usage.Inodes, usage.InodesPercentUsed(), usage.InodesFree))
+			b.WriteString(fmt.Sprintf("  Reported:  by node %s\n", usage.Node))
+		}
+		b.WriteRune('\n')
+
+		for i, action := range volumeActions(m.selectedVolume) {
+			cursor := " "
+			if m.Cursor == i {
+				cursor = ">"
+			}
+			b.WriteString(fmt.Sprintf("%s %s\n", cursor, action))
+		}
+
+	case VolumeSizeInput:
+		b.WriteString(fmt.Sprintf("Resize volume %s/%s (current size: %s)\n\n",
+			m.selectedVolume.Namespace, m.selectedVolume.Name, m.selectedVolume.Size))
+		b.WriteString(fmt.Sprintf("New size: %s_\n", m.newVolumeSize))
+
+	case SnapshotClassMenu:
+		b.WriteString(m.renderSnapshotClasses())
+
+	case SnapshotListMenu:
+		b.WriteString(m.renderSnapshots())
+
+	case SnapshotRestoreInput:
+		b.WriteString(fmt.Sprintf("Restore snapshot %s\n\n", m.selectedSnapshot.Name))
+		b.WriteString(fmt.Sprintf("New PVC name: %s_\n", m.snapshotPVCName))
+
+	case PVListView, ReclaimPolicyConfirm:
+		b.WriteString(m.renderPersistentVolumes())
+
+	case StorageClassListView:
+		b.WriteString(m.renderStorageClasses())
+
+	case StuckDiagnosisView, FinalizerRemoveInput:
+		b.WriteString(m.renderDiagnosis())
+
+	case MigrationClassMenu:
+		b.WriteString(m.renderMigrationClasses())
+
+	case MigrationFinalizeConfirm:
+		b.WriteString(fmt.Sprintf("Finalize migration of %s/%s\n", m.selectedVolume.Namespace, m.selectedVolume.Name))
+
+	case StorageWasteView, StorageWasteConfirm:
+		b.WriteString(m.renderStorageWaste())
+
+	case WorkloadRestoreConfirm:
+		b.WriteString(renderPausedWorkloads(m.pausedWorkloads))
+
+	case VolumeDeleteConfirm:
+		if m.volumePlan != nil {
+			b.WriteString(renderVolumePlan(m.volumePlan))
+		}
 	}
 
 	if m.Message != "" {
@@ -54,10 +143,51 @@ func (m *Model) View() string {
 		b.WriteRune('\n')
 	}
 
+	if m.State == RenewalForm {
+		b.WriteString("\n(↑/↓ or tab to move between fields, enter to review, esc to cancel)\n")
+		return b.String()
+	}
+
+	if m.State == VolumeSizeInput || m.State == SnapshotRestoreInput || m.State == FinalizerRemoveInput || m.State == CertCAInput || m.State == CSRDecisionInput || m.State == LocalCAInput ||
+		m.State == IngressProbeInput || m.State == CertExportInput || m.State == TokenDeleteInput {
+		b.WriteString("\n(enter to apply, esc to cancel)\n")
+		return b.String()
+	}
+
 	b.WriteString("\n(↑/↓ or j/k to move, enter to select")
-	if m.State == ListSubMenu {
+	if m.State == ListSubMenu || m.isVolumeState() {
 		b.WriteString(", backspace to go back")
 	}
+	if m.State == ListSubMenu && m.Choices[m.lastMainCursor] == "certificates" {
+		b.WriteString(", s to sort by expiry or name, e to export")
+	}
+	if m.State == VolumeResizeMenu {
+		b.WriteString(", tab to switch view, v to show PV, c to show StorageClass, r to restore paused workloads")
+	}
+	if m.State == PVListView {
+		b.WriteString(", tab to switch view, enter to change reclaim policy, d to diagnose, p to show PVC, c to show StorageClass")
+	}
+	if m.State == CertDetailView {
+		b.WriteString(", r to renew, l to re-issue with a local CA, u to refresh, v to verify against a CA secret, backspace to go back")
+	}
+	if m.State == CSRListView {
+		b.WriteString(", a to approve, d to deny, r to refresh, backspace to go back")
+	}
+	if m.State == IngressTLSView {
+		b.WriteString(", p to probe the hosts, r to refresh, backspace to go back")
+	}
+	if m.State == TokenListView {
+		b.WriteString(", d to delete an unused token, r to refresh, backspace to go back")
+	}
+	if m.State == StuckDiagnosisView {
+		b.WriteString(", enter on a finalizer to remove it, r to refresh")
+	}
+	if m.State == StorageClassListView {
+		b.WriteString(", tab to switch view")
+	}
+	if m.State == StorageWasteView {
+		b.WriteString(", space to mark, a to mark all, d to delete marked, backspace to go back")
+	}
 	b.WriteString(", q to quit)\n")
 
 	return b.String()
@@ -0,0 +1,289 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"kubegreen/internal/controller"
+
+	tea "github.com/charmbracelet/bubbletea"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func (m *Model) isBrowserState() bool {
+	switch m.State {
+	case PVListView, StorageClassListView, ReclaimPolicyConfirm:
+		return true
+	}
+	return false
+}
+
+func (m *Model) loadPersistentVolumes() {
+	pvs, err := m.volumeCtl.ListPersistentVolumes()
+	if err != nil {
+		m.Message = fmt.Sprintf("Error listing persistent volumes: %v", err)
+		return
+	}
+	m.persistentVols = pvs
+	m.State = PVListView
+	m.Cursor = 0
+	m.Message = ""
+}
+
+func (m *Model) loadStorageClasses() {
+	classes, err := m.volumeCtl.ListStorageClasses()
+	if err != nil {
+		m.Message = fmt.Sprintf("Error listing storage classes: %v", err)
+		return
+	}
+	m.storageClasses = classes
+	m.State = StorageClassListView
+	m.Cursor = 0
+	m.Message = ""
+}
+
+func (m *Model) jumpToVolume(namespace, name string) {
+	if name == "" {
+		m.Message = "The PV is not bound to a claim"
+		return
+	}
+	m.loadVolumes()
+	if m.State != VolumeResizeMenu {
+		return
+	}
+	for i, v := range m.volumes {
+		if v.Namespace == namespace && v.Name == name {
+			m.Cursor = i
+			m.lastVolumeCursor = i
+			return
+		}
+	}
+	m.Message = fmt.Sprintf("PVC %s/%s not found", namespace, name)
+}
+
+func (m *Model) jumpToPersistentVolume(name string) {
+	if name == "" {
+		m.Message = "The PVC is not bound to a PV"
+		return
+	}
+	m.loadPersistentVolumes()
+	if m.State != PVListView {
+		return
+	}
+	for i, pv := range m.persistentVols {
+		if pv.Name == name {
+			m.Cursor = i
+			return
+		}
+	}
+	m.Message = fmt.Sprintf("PV %s not found", name)
+}
+
+func (m *Model) jumpToStorageClass(name string) {
+	if name == "" {
+		m.Message = "No StorageClass set"
+		return
+	}
+	m.loadStorageClasses()
+	if m.State != StorageClassListView {
+		return
+	}
+	for i, class := range m.storageClasses {
+		if class.Name == name {
+			m.Cursor = i
+			return
+		}
+	}
+	m.Message = fmt.Sprintf("StorageClass %s not found", name)
+}
+
+// toggledReclaimPolicy returns the policy offered when changing a PV's
+// reclaim policy
+func toggledReclaimPolicy(policy corev1.PersistentVolumeReclaimPolicy) corev1.PersistentVolumeReclaimPolicy {
+	if policy == corev1.PersistentVolumeReclaimRetain {
+		return corev1.PersistentVolumeReclaimDelete
+	}
+	return corev1.PersistentVolumeReclaimRetain
+}
+
+func (m *Model) handleVolumeBrowser(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.State == ReclaimPolicyConfirm {
+		pv := m.persistentVols[m.Cursor]
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "y", "Y":
+			policy := toggledReclaimPolicy(pv.ReclaimPolicy)
+			cursor := m.Cursor
+			if err := m.volumeCtl.SetReclaimPolicy(pv.Name, policy); err != nil {
+				m.State = PVListView
+				m.Message = fmt.Sprintf("Failed to change reclaim policy:\n%v", err)
+				return m, nil
+			}
+			m.loadPersistentVolumes()
+			m.Cursor = bound(cursor, 0, len(m.persistentVols)-1)
+			m.Message = fmt.Sprintf("Reclaim policy of PV %s set to %s", pv.Name, policy)
+		case "n", "N", "esc", "backspace":
+			m.State = PVListView
+			m.Message = "Reclaim policy unchanged"
+		}
+		return m, nil
+	}
+
+	length := len(m.persistentVols)
+	if m.State == StorageClassListView {
+		length = len(m.storageClasses)
+	}
+
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.Cursor > 0 {
+			m.Cursor--
+		}
+	case "down", "j":
+		if m.Cursor < length-1 {
+			m.Cursor++
+		}
+	case "tab":
+		if m.State == PVListView {
+			m.loadStorageClasses()
+		} else {
+			m.Message = ""
+			m.loadVolumes()
+		}
+	case "enter":
+		if m.State == PVListView && length > 0 {
+			pv := m.persistentVols[m.Cursor]
+			m.Message = fmt.Sprintf("Change reclaim policy of PV %s from %s to %s? (y/n)",
+				pv.Name, pv.ReclaimPolicy, toggledReclaimPolicy(pv.ReclaimPolicy))
+			m.State = ReclaimPolicyConfirm
+		}
+	case "p":
+		if m.State == PVListView && length > 0 {
+			pv := m.persistentVols[m.Cursor]
+			m.jumpToVolume(pv.ClaimNamespace, pv.ClaimName)
+		}
+	case "d":
+		if m.State == PVListView && length > 0 {
+			name := m.persistentVols[m.Cursor].Name
+			m.diagnose(func() (*controller.StuckDiagnosis, error) {
+				return m.volumeCtl.DiagnosePV(name)
+			})
+		}
+	case "c":
+		if m.State == PVListView && length > 0 {
+			m.jumpToStorageClass(m.persistentVols[m.Cursor].StorageClass)
+		}
+	case "esc", "backspace":
+		m.State = MainMenu
+		m.Cursor = m.lastMainCursor
+		m.Message = ""
+	}
+	return m, nil
+}
+
+// renderVolumeTabs shows which of the PVC, PV and StorageClass views is
+// active
+func (m *Model) renderVolumeTabs() string {
+	tabs := []struct {
+		name   string
+		active bool
+	}{
+		{"PVCs", m.State == VolumeResizeMenu},
+		{"PVs", m.State == PVListView || m.State == ReclaimPolicyConfirm},
+		{"StorageClasses", m.State == StorageClassListView},
+	}
+
+	var parts []string
+	for _, tab := range tabs {
+		if tab.active {
+			parts = append(parts, "["+tab.name+"]")
+		} else {
+			parts = append(parts, " "+tab.name+" ")
+		}
+	}
+	return strings.Join(parts, " ") + "\n\n"
+}
+
+func (m *Model) renderPersistentVolumes() string {
+	var b strings.Builder
+	b.WriteString(m.renderVolumeTabs())
+	if len(m.persistentVols) == 0 {
+		b.WriteString("No persistent volumes found\n")
+		return b.String()
+	}
+
+	for i, pv := range m.persistentVols {
+		cursor := " "
+		if m.Cursor == i {
+			cursor = ">"
+		}
+		claim := "-"
+		if pv.ClaimName != "" {
+			claim = pv.ClaimNamespace + "/" + pv.ClaimName
+		}
+		b.WriteString(fmt.Sprintf("%s %-45s %-10s %-7s %8s  %s\n", cursor, pv.Name, pv.Phase, pv.ReclaimPolicy, pv.Capacity, claim))
+	}
+
+	pv := m.persistentVols[m.Cursor]
+	b.WriteString(fmt.Sprintf("\nPV %s:\n", pv.Name))
+	b.WriteString(fmt.Sprintf("  StorageClass:   %s\n", orDash(pv.StorageClass)))
+	b.WriteString(fmt.Sprintf("  Access modes:   %s\n", joinAccessModes(pv.AccessModes)))
+	b.WriteString(fmt.Sprintf("  CSI driver:     %s\n", orDash(pv.CSIDriver)))
+	b.WriteString(fmt.Sprintf("  Volume handle:  %s\n", orDash(pv.VolumeHandle)))
+	if len(pv.NodeAffinity) == 0 {
+		b.WriteString("  Node affinity:  -\n")
+	}
+	for i, term := range pv.NodeAffinity {
+		label := "  Node affinity:  "
+		if i > 0 {
+			label = "              or "
+		}
+		b.WriteString(label + term + "\n")
+	}
+	return b.String()
+}
+
+func (m *Model) renderStorageClasses() string {
+	var b strings.Builder
+	b.WriteString(m.renderVolumeTabs())
+	if len(m.storageClasses) == 0 {
+		b.WriteString("No storage classes found\n")
+		return b.String()
+	}
+
+	for i, class := range m.storageClasses {
+		cursor := " "
+		if m.Cursor == i {
+			cursor = ">"
+		}
+		def := ""
+		if class.IsDefault {
+			def = "(default)"
+		}
+		expansion := "no expansion"
+		if class.AllowVolumeExpansion {
+			expansion = "expandable"
+		}
+		b.WriteString(fmt.Sprintf("%s %-30s %-9s %-35s %-20s %-7s %s\n", cursor, class.Name, def,
+			class.Provisioner, class.VolumeBindingMode, class.ReclaimPolicy, expansion))
+	}
+	return b.String()
+}
+
+func joinAccessModes(modes []corev1.PersistentVolumeAccessMode) string {
+	var names []string
+	for _, mode := range modes {
+		names = append(names, string(mode))
+	}
+	return orDash(strings.Join(names, ", "))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
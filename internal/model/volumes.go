@@ -0,0 +1,480 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"kubegreen/internal/controller"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// volumeActions returns the actions offered for a volume. Only migrated
+// volumes can have their migration finalized
+func volumeActions(volume *controller.VolumeInfo) []string {
+	actions := []string{"resize", "snapshot", "snapshots", "migrate"}
+	if volume.MigratedTo() != "" {
+		actions = append(actions, "finalize migration")
+	}
+	return append(actions, "diagnose", "delete")
+}
+
+func (m *Model) isVolumeState() bool {
+	switch m.State {
+	case VolumeResizeMenu, VolumeActionMenu, VolumeSizeInput, VolumeDeleteConfirm, WorkloadRestoreConfirm,
+		SnapshotClassMenu, SnapshotListMenu, SnapshotRestoreInput,
+		MigrationClassMenu, MigrationFinalizeConfirm,
+		PVListView, StorageClassListView, ReclaimPolicyConfirm,
+		StuckDiagnosisView, FinalizerRemoveInput:
+		return true
+	}
+	return false
+}
+
+func (m *Model) loadVolumes() {
+	volumes, err := m.volumeCtl.ListVolumes()
+	if err != nil {
+		m.Message = fmt.Sprintf("Error listing volumes: %v", err)
+		m.State = MainMenu
+		m.Cursor = m.lastMainCursor
+		return
+	}
+	m.volumes = volumes
+	m.renderVolumeChoices()
+	m.State = VolumeResizeMenu
+	m.Cursor = bound(m.lastVolumeCursor, 0, len(m.SubChoices)-1)
+	// Update collects the usage in the background, the list shows the last
+	// one until it arrives
+	m.volumeUsagePending = true
+}
+
+func (m *Model) renderVolumeChoices() {
+	controller.ApplyVolumeUsage(m.volumes, m.volumeUsage)
+	var choices []string
+	for _, v := range m.volumes {
+		choices = append(choices, fmt.Sprintf("%s/%s (%s) %s", v.Namespace, v.Name, v.Size, m.formatVolumeUsage(v.Usage)))
+	}
+	m.SubChoices = choices
+}
+
+// volumeUsageMsg carries the usage collected by loadVolumeUsage. Results of
+// an earlier collection carry an older id and are dropped
+type volumeUsageMsg struct {
+	id    int
+	usage map[string]controller.VolumeUsage
+	err   error
+}
+
+// loadVolumeUsage collects the usage of the mounted volumes from the
+// kubelets, cancelling the collection still running
+func (m *Model) loadVolumeUsage() tea.Cmd {
+	m.cancelVolumeUsage()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.volumeUsageCancel = cancel
+	m.volumeUsageID++
+	id, volumeCtl := m.volumeUsageID, m.volumeCtl
+	return func() tea.Msg {
+		usage, err := volumeCtl.CollectVolumeUsage(ctx)
+		return volumeUsageMsg{id: id, usage: usage, err: err}
+	}
+}
+
+func (m *Model) cancelVolumeUsage() {
+	if m.volumeUsageCancel != nil {
+		m.volumeUsageCancel()
+		m.volumeUsageCancel = nil
+	}
+}
+
+func (m *Model) handleVolumeUsage(msg volumeUsageMsg) (tea.Model, tea.Cmd) {
+	if msg.id != m.volumeUsageID {
+		return m, nil
+	}
+	m.cancelVolumeUsage()
+	m.volumeUsageErr = msg.err
+	if msg.err == nil {
+		m.volumeUsage = msg.usage
+	}
+	if m.State != VolumeResizeMenu {
+		return m, nil
+	}
+	m.renderVolumeChoices()
+	if msg.err != nil && m.Message == "" {
+		m.Message = fmt.Sprintf("Volume usage unavailable: %v", msg.err)
+	}
+	return m, nil
+}
+
+// formatVolumeUsage renders the used share of a volume, flagging volumes
+// whose capacity or inodes are used above the configured threshold
+func (m *Model) formatVolumeUsage(usage *controller.VolumeUsage) string {
+	if usage == nil {
+		switch {
+		case m.volumeUsage == nil && m.volumeUsageErr != nil:
+			return "- usage unavailable"
+		case m.volumeUsage == nil:
+			return "- loading usage"
+		}
+		return "- not mounted"
+	}
+	text := fmt.Sprintf("- %s used of %s (%.0f%%)", formatBytes(usage.UsedBytes), formatBytes(usage.CapacityBytes), usage.PercentUsed())
+	threshold := m.config.VolumeUsageThreshold
+	switch {
+	case usage.PercentUsed() >= threshold:
+		return warningStyle.Render(text + " !")
+	case usage.InodesPercentUsed() >= threshold:
+		return warningStyle.Render(fmt.Sprintf("%s, inodes %.0f%% !", text, usage.InodesPercentUsed()))
+	}
+	return text
+}
+
+func (m *Model) handleVolumeMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	if m.State == VolumeSizeInput {
+		return m.handleVolumeSizeInput(keyMsg)
+	}
+	if m.State == SnapshotRestoreInput {
+		return m.handleSnapshotRestoreInput(keyMsg)
+	}
+	if m.isDiagnosisState() {
+		return m.handleDiagnosis(keyMsg)
+	}
+	if m.isBrowserState() {
+		return m.handleVolumeBrowser(keyMsg)
+	}
+
+	switch keyMsg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.Cursor > 0 {
+			m.Cursor--
+		}
+	case "down", "j":
+		if m.Cursor < m.volumeMenuLen()-1 {
+			m.Cursor++
+		}
+	case "enter":
+		return m, m.handleVolumeEnter()
+	case "tab":
+		if m.State == VolumeResizeMenu {
+			m.loadPersistentVolumes()
+		}
+	case "v":
+		if m.State == VolumeResizeMenu && len(m.volumes) > 0 {
+			m.jumpToPersistentVolume(m.volumes[m.Cursor].VolumeName)
+		}
+	case "c":
+		if m.State == VolumeResizeMenu && len(m.volumes) > 0 {
+			m.jumpToStorageClass(m.volumes[m.Cursor].StorageClass)
+		}
+	case "y", "Y":
+		if m.State == VolumeDeleteConfirm {
+			return m, m.deleteSelectedVolume(controller.DeleteVolumeOptions{})
+		}
+		if m.State == MigrationFinalizeConfirm {
+			return m, m.finalizeMigration()
+		}
+		if m.State == WorkloadRestoreConfirm {
+			return m, m.restorePausedWorkloads()
+		}
+	case "s", "S":
+		if m.State == VolumeDeleteConfirm {
+			return m, m.deleteSelectedVolume(controller.DeleteVolumeOptions{SkipRestore: true})
+		}
+	case "r":
+		if m.State == VolumeResizeMenu && len(m.volumes) > 0 {
+			m.confirmRestoreWorkloads(m.volumes[m.Cursor].Namespace)
+		}
+	case "n", "N":
+		if m.State == VolumeDeleteConfirm {
+			m.Message = "Volume deletion cancelled"
+			m.backToVolumeList()
+		}
+		if m.State == MigrationFinalizeConfirm {
+			m.Message = fmt.Sprintf("PVC %s kept, finalize the migration later from its actions", m.selectedVolume.Name)
+			m.backToVolumeList()
+		}
+		if m.State == WorkloadRestoreConfirm {
+			m.Message = "Workloads kept paused"
+			m.backToVolumeList()
+		}
+	case "esc", "backspace":
+		if m.State == VolumeResizeMenu {
+			m.cancelVolumeUsage()
+			m.State = MainMenu
+			m.Cursor = m.lastMainCursor
+			m.Message = ""
+			return m, nil
+		}
+		m.Message = ""
+		m.backToVolumeList()
+	}
+	return m, nil
+}
+
+func (m *Model) volumeMenuLen() int {
+	switch m.State {
+	case VolumeActionMenu:
+		return len(volumeActions(m.selectedVolume))
+	case SnapshotClassMenu:
+		return len(m.snapshotClasses)
+	case SnapshotListMenu:
+		return len(m.snapshots)
+	case MigrationClassMenu:
+		return len(m.storageClasses)
+	}
+	return len(m.SubChoices)
+}
+
+func (m *Model) handleVolumeEnter() tea.Cmd {
+	switch m.State {
+	case VolumeResizeMenu:
+		if len(m.volumes) == 0 {
+			return nil
+		}
+		m.lastVolumeCursor = m.Cursor
+		m.selectedVolume = &m.volumes[m.Cursor]
+		m.State = VolumeActionMenu
+		m.Cursor = 0
+		m.Message = ""
+	case VolumeActionMenu:
+		switch volumeActions(m.selectedVolume)[m.Cursor] {
+		case "resize":
+			m.newVolumeSize = ""
+			m.Message = "Enter new size (e.g., 10Gi):"
+			m.State = VolumeSizeInput
+		case "snapshot":
+			m.loadSnapshotClasses()
+		case "snapshots":
+			m.loadSnapshots()
+		case "migrate":
+			m.loadMigrationClasses()
+		case "diagnose":
+			volume := m.selectedVolume
+			m.diagnose(func() (*controller.StuckDiagnosis, error) {
+				return m.volumeCtl.DiagnosePVC(volume.Namespace, volume.Name)
+			})
+		case "finalize migration":
+			m.Message = fmt.Sprintf("Delete the migrated PVC %s/%s, its data is in %s? (y/n)",
+				m.selectedVolume.Namespace, m.selectedVolume.Name, m.selectedVolume.MigratedTo())
+			m.State = MigrationFinalizeConfirm
+		case "delete":
+			plan, err := m.volumeCtl.PlanDeleteVolume(m.selectedVolume.Namespace, m.selectedVolume.Name)
+			if err != nil {
+				m.Message = fmt.Sprintf("Failed to plan volume deletion:\n%v", err)
+				return nil
+			}
+			m.volumePlan = plan
+			if err := plan.Executable(); err != nil {
+				m.Message = fmt.Sprintf("This plan cannot be executed: %v\n(n to go back)", err)
+			} else {
+				m.Message = "Execute this plan?\n" +
+					"(y = delete and restore workloads, s = delete and keep workloads paused, n = cancel)"
+			}
+			m.State = VolumeDeleteConfirm
+		}
+	case SnapshotClassMenu:
+		if len(m.snapshotClasses) > 0 {
+			return m.createSnapshot(m.snapshotClasses[m.Cursor].Name)
+		}
+	case SnapshotListMenu:
+		if len(m.snapshots) > 0 {
+			m.selectedSnapshot = &m.snapshots[m.Cursor]
+			m.snapshotPVCName = m.selectedSnapshot.SourcePVC + "-restore"
+			m.Message = "Enter the name of the new PVC:"
+			m.State = SnapshotRestoreInput
+		}
+	case MigrationClassMenu:
+		if len(m.storageClasses) > 0 {
+			return m.migrateSelectedVolume(m.storageClasses[m.Cursor].Name)
+		}
+	}
+	return nil
+}
+
+func (m *Model) deleteSelectedVolume(opts controller.DeleteVolumeOptions) tea.Cmd {
+	if m.volumePlan == nil {
+		return nil
+	}
+	plan, volumeCtl := m.volumePlan, m.volumeCtl
+	m.volumePlan = nil
+
+	title := fmt.Sprintf("Deleting volume %s/%s", plan.Namespace, plan.PVCName)
+	return m.startOperation(title, func(ctx context.Context, progress controller.ProgressFunc) error {
+		return volumeCtl.ExecuteDeletePlan(ctx, plan, opts, progress)
+	}, func(err error) {
+		if err != nil {
+			m.Message = fmt.Sprintf("Failed to delete volume:\n%v", err)
+		} else {
+			m.Message = fmt.Sprintf("Successfully deleted volume %s/%s", plan.Namespace, plan.PVCName)
+		}
+		m.backToVolumeList()
+	})
+}
+
+// confirmRestoreWorkloads asks to restore the workloads left paused in
+// namespace by a volume deletion
+func (m *Model) confirmRestoreWorkloads(namespace string) {
+	paused, err := m.volumeCtl.ListPausedWorkloads(namespace, "")
+	if err != nil {
+		m.Message = fmt.Sprintf("Failed to list paused workloads:\n%v", err)
+		return
+	}
+	if len(paused) == 0 {
+		m.Message = fmt.Sprintf("No paused workloads in namespace %s", namespace)
+		return
+	}
+	m.lastVolumeCursor = m.Cursor
+	m.pausedWorkloads = paused
+	m.Message = fmt.Sprintf("Restore these %d workload(s) of namespace %s? (y/n)", len(paused), namespace)
+	m.State = WorkloadRestoreConfirm
+}
+
+func (m *Model) restorePausedWorkloads() tea.Cmd {
+	paused, volumeCtl := m.pausedWorkloads, m.volumeCtl
+	m.pausedWorkloads = nil
+
+	title := fmt.Sprintf("Restoring paused workloads of namespace %s", paused[0].Namespace)
+	return m.startOperation(title, func(ctx context.Context, progress controller.ProgressFunc) error {
+		_, err := volumeCtl.RestoreWorkloads(ctx, paused, progress)
+		return err
+	}, func(err error) {
+		if err != nil {
+			m.Message = fmt.Sprintf("Failed to restore workloads:\n%v", err)
+		} else {
+			m.Message = fmt.Sprintf("Restored %d paused workload(s)", len(paused))
+		}
+		m.backToVolumeList()
+	})
+}
+
+func renderPausedWorkloads(paused []controller.PausedWorkload) string {
+	var b strings.Builder
+	b.WriteString("Paused workloads to restore:\n")
+	for _, p := range paused {
+		b.WriteString(fmt.Sprintf("  - %s in %s (paused for PVC %s)\n", p.Workload, p.Namespace, p.PVCName))
+	}
+	b.WriteRune('\n')
+	return b.String()
+}
+
+func (m *Model) handleVolumeSizeInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.Message = "Volume resize cancelled"
+		m.backToVolumeList()
+	case tea.KeyEnter:
+		if m.newVolumeSize == "" || m.selectedVolume == nil {
+			return m, nil
+		}
+		namespace, name, size := m.selectedVolume.Namespace, m.selectedVolume.Name, m.newVolumeSize
+		volumeCtl := m.volumeCtl
+		title := fmt.Sprintf("Resizing volume %s/%s to %s", namespace, name, size)
+		return m, m.startOperation(title, func(ctx context.Context, progress controller.ProgressFunc) error {
+			return volumeCtl.ResizeVolume(ctx, namespace, name, size, progress)
+		}, func(err error) {
+			if err != nil {
+				m.Message = fmt.Sprintf("Failed to resize volume:\n%v", err)
+			} else {
+				m.Message = fmt.Sprintf("Successfully resized volume %s/%s to %s", namespace, name, size)
+			}
+			m.backToVolumeList()
+		})
+	default:
+		m.newVolumeSize = editText(m.newVolumeSize, msg)
+	}
+	return m, nil
+}
+
+// editText applies a key press to the value of a single-line text input
+func editText(value string, msg tea.KeyMsg) string {
+	switch msg.Type {
+	case tea.KeyBackspace:
+		if len(value) > 0 {
+			return value[:len(value)-1]
+		}
+	case tea.KeyRunes:
+		return value + string(msg.Runes)
+	}
+	return value
+}
+
+func (m *Model) backToVolumeList() {
+	message := m.Message
+	m.newVolumeSize = ""
+	m.loadVolumes()
+	if m.State == VolumeResizeMenu {
+		m.Message = message
+	}
+}
+
+func renderVolumePlan(plan *controller.VolumeDeletePlan) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("Delete plan for PVC %s/%s (%s, %s)\n\n", plan.Namespace, plan.PVCName, plan.Size, plan.Phase))
+
+	b.WriteString("Workloads to pause:\n")
+	if len(plan.Workloads) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, workload := range plan.Workloads {
+		switch workload.Kind {
+		case controller.KindDeployment, controller.KindStatefulSet, controller.KindReplicaSet:
+			b.WriteString(fmt.Sprintf("  - %s: scale %d -> 0\n", workload, workload.Replicas))
+		case controller.KindDaemonSet:
+			b.WriteString(fmt.Sprintf("  - %s: unschedule from all nodes\n", workload))
+		case controller.KindCronJob:
+			b.WriteString(fmt.Sprintf("  - %s: suspend and delete active jobs\n", workload))
+		case controller.KindJob:
+			b.WriteString(fmt.Sprintf("  - %s: delete\n", workload))
+		default:
+			b.WriteString(fmt.Sprintf("  - %s: unsupported\n", workload))
+		}
+	}
+
+	b.WriteString("Pods to terminate:\n")
+	if len(plan.Pods) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, pod := range plan.Pods {
+		owner := pod.Owner
+		if owner == "" {
+			owner = "bare pod, deleted directly"
+		}
+		b.WriteString(fmt.Sprintf("  - %s (%s)\n", pod.Name, owner))
+	}
+
+	if plan.PVName == "" {
+		b.WriteString("Bound PV: none\n")
+	} else {
+		data := "data is kept"
+		if plan.DataDestroyed {
+			data = "DATA WILL BE DESTROYED"
+		}
+		b.WriteString(fmt.Sprintf("Bound PV: %s (reclaim policy %s, %s)\n", plan.PVName, plan.ReclaimPolicy, data))
+	}
+
+	b.WriteString("Snapshots:\n")
+	if len(plan.Snapshots) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, snapshot := range plan.Snapshots {
+		ready := "not ready"
+		if snapshot.ReadyToUse {
+			ready = "ready"
+		}
+		b.WriteString(fmt.Sprintf("  - %s (%s, %s)\n", snapshot.Name, snapshot.CreationTime.Format("2006-01-02 15:04:05"), ready))
+	}
+
+	for _, warning := range plan.Warnings {
+		b.WriteString(fmt.Sprintf("! %s\n", warning))
+	}
+	return b.String()
+}